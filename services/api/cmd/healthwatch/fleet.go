@@ -0,0 +1,136 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Autherain/go_cyber/internal/health"
+)
+
+// instance is the latest known state of a service replica
+type instance struct {
+	Service       string    `json:"service"`
+	Instance      string    `json:"instance"`
+	Status        string    `json:"status"`
+	Ready         bool      `json:"ready"`
+	Version       string    `json:"version"`
+	LastSeen      time.Time `json:"lastSeen"`
+	Stale         bool      `json:"stale"`
+	FailingChecks []string  `json:"failingChecks,omitempty"`
+}
+
+// ok reports whether the instance is neither unhealthy nor stale. Degraded instances are fine.
+func (i *instance) ok() bool {
+	return !i.Stale && i.Status != health.StatusUnhealthy
+}
+
+type instanceKey struct {
+	service  string
+	instance string
+}
+
+// fleet keeps the live table of the service instances publishing their health status
+type fleet struct {
+	mu         sync.Mutex
+	instances  map[instanceKey]instance
+	staleAfter time.Duration
+}
+
+func newFleet(staleAfter time.Duration) *fleet {
+	return &fleet{instances: make(map[instanceKey]instance), staleAfter: staleAfter}
+}
+
+// update records a health status received at the given time
+func (f *fleet) update(status *health.Status, receivedAt time.Time) {
+	var failing []string
+	for name, result := range status.Checks {
+		if result.Status != health.StatusHealthy {
+			failing = append(failing, name)
+		}
+	}
+	slices.Sort(failing)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.instances[instanceKey{service: status.ServiceName, instance: status.Instance}] = instance{
+		Service:       status.ServiceName,
+		Instance:      status.Instance,
+		Status:        status.Status,
+		Ready:         status.Ready,
+		Version:       status.Version,
+		LastSeen:      receivedAt,
+		FailingChecks: failing,
+	}
+}
+
+// snapshot returns the instances sorted by service and instance, flagging those not heard of for too long as stale
+func (f *fleet) snapshot(now time.Time) []instance {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	instances := make([]instance, 0, len(f.instances))
+	for _, i := range f.instances {
+		i.Stale = now.Sub(i.LastSeen) > f.staleAfter
+		instances = append(instances, i)
+	}
+
+	slices.SortFunc(instances, func(a, b instance) int {
+		return cmp.Or(cmp.Compare(a.Service, b.Service), cmp.Compare(a.Instance, b.Instance))
+	})
+	return instances
+}
+
+// allOK reports whether at least one instance was seen and none is unhealthy or stale
+func allOK(instances []instance) bool {
+	if len(instances) == 0 {
+		return false
+	}
+	for i := range instances {
+		if !instances[i].ok() {
+			return false
+		}
+	}
+	return true
+}
+
+func writeJSON(w io.Writer, instances []instance) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(instances)
+}
+
+func writeTable(w io.Writer, instances []instance, now time.Time) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVICE\tINSTANCE\tSTATUS\tREADY\tVERSION\tLAST SEEN\tFAILING CHECKS")
+
+	for _, i := range instances {
+		status := i.Status
+		if i.Stale {
+			status = "stale (" + status + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%t\t%s\t%s ago\t%s\n",
+			i.Service, i.Instance, status, i.Ready, i.Version,
+			now.Sub(i.LastSeen).Truncate(time.Second), joinOrDash(i.FailingChecks),
+		)
+	}
+
+	if len(instances) == 0 {
+		fmt.Fprintln(tw, "(no instance seen yet)")
+	}
+	return tw.Flush()
+}
+
+func joinOrDash(values []string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	return strings.Join(values, ",")
+}
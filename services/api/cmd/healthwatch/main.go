@@ -0,0 +1,151 @@
+// Command healthwatch aggregates the health statuses published by every service replica on NATS.
+//
+// It subscribes to the health status topic, keeps a live table of the service instances and flags those which stopped
+// publishing as stale. By default it collects statuses for a while, prints the table once and exits with status 1 if
+// any instance is unhealthy or stale, or if none was seen, which suits deploy scripts. With -watch it keeps refreshing
+// the table until interrupted.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Autherain/go_cyber/internal/health"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	exitOK        = 0
+	exitUnhealthy = 1
+	exitError     = 2
+
+	clearScreen = "\033[H\033[2J"
+)
+
+type options struct {
+	natsURL     string
+	statusTopic string
+	collect     time.Duration
+	staleAfter  time.Duration
+	refresh     time.Duration
+	watch       bool
+	format      string
+}
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	opts := parseFlags()
+
+	conn, err := nats.Connect(opts.natsURL, nats.Name("healthwatch"))
+	if err != nil {
+		slog.Error("Could not connect to NATS", "error", err)
+		return exitError
+	}
+	defer conn.Close()
+
+	instances := newFleet(opts.staleAfter)
+	sub, err := conn.Subscribe(opts.statusTopic+".>", func(msg *nats.Msg) {
+		var status health.Status
+		if err := json.Unmarshal(msg.Data, &status); err != nil {
+			slog.Warn("Ignoring malformed health status", "subject", msg.Subject, "error", err)
+			return
+		}
+		instances.update(&status, time.Now())
+	})
+	if err != nil {
+		slog.Error("Could not subscribe to health statuses", "error", err)
+		return exitError
+	}
+	defer sub.Unsubscribe()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if opts.watch {
+		return watch(ctx, instances, opts)
+	}
+
+	select {
+	case <-time.After(opts.collect):
+	case <-ctx.Done():
+	}
+	return report(os.Stdout, instances, opts.format)
+}
+
+func parseFlags() *options {
+	natsURL := os.Getenv("APP_NATS_URL")
+	if natsURL == "" {
+		natsURL = nats.DefaultURL
+	}
+
+	opts := &options{}
+	flag.StringVar(&opts.natsURL, "nats", natsURL, "NATS server URL (defaults to APP_NATS_URL)")
+	flag.StringVar(&opts.statusTopic, "topic", "health.status", "health status topic prefix")
+	flag.DurationVar(&opts.collect, "collect", 15*time.Second, "time spent collecting statuses before reporting")
+	flag.DurationVar(&opts.staleAfter, "stale", 30*time.Second, "time after which a silent instance is stale")
+	flag.DurationVar(&opts.refresh, "refresh", time.Second, "table refresh interval in watch mode")
+	flag.BoolVar(&opts.watch, "watch", false, "keep refreshing the table until interrupted")
+	flag.StringVar(&opts.format, "format", "text", "output format: text or json")
+	flag.Parse()
+
+	return opts
+}
+
+// watch refreshes the table until the context is done, then exits according to the last state
+func watch(ctx context.Context, instances *fleet, opts *options) int {
+	ticker := time.NewTicker(opts.refresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if opts.format == "text" {
+				fmt.Print(clearScreen)
+			}
+			if code := report(os.Stdout, instances, opts.format); code == exitError {
+				return code
+			}
+		case <-ctx.Done():
+			if allOK(instances.snapshot(time.Now())) {
+				return exitOK
+			}
+			return exitUnhealthy
+		}
+	}
+}
+
+// report writes the instances table and returns the matching exit code
+func report(w io.Writer, instances *fleet, format string) int {
+	now := time.Now()
+	snapshot := instances.snapshot(now)
+
+	var err error
+	switch format {
+	case "json":
+		err = writeJSON(w, snapshot)
+	case "text":
+		err = writeTable(w, snapshot, now)
+	default:
+		err = fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		slog.Error("Could not write report", "error", err)
+		return exitError
+	}
+
+	if allOK(snapshot) {
+		return exitOK
+	}
+	return exitUnhealthy
+}
@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
@@ -10,59 +13,177 @@ import (
 	"github.com/Autherain/go_cyber/environment"
 	"github.com/Autherain/go_cyber/internal/health"
 	"github.com/Autherain/go_cyber/internal/logger"
+	"github.com/Autherain/go_cyber/internal/metrics"
+	"github.com/Autherain/go_cyber/internal/pagination"
+	"github.com/Autherain/go_cyber/internal/tracing"
 	"github.com/Autherain/go_cyber/pkg/server"
 	"github.com/Autherain/go_cyber/store"
 	"github.com/jirenius/go-res"
+	"github.com/nats-io/nats.go"
 
 	_ "github.com/lib/pq"
 )
 
-const serviceName = "api"
+const (
+	serviceName     = "api"
+	migrationsTable = "schema_migrations"
+)
 
 func main() {
+	os.Exit(run())
+}
+
+// run starts the service and returns its exit code once stopped, the deferred cleanups, such as flushing the traces
+// and closing the log file, running before the process exits.
+func run() int {
 	// Parse environment variables
-	variables := environment.Parse()
+	variables, err := environment.Parse(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if variables.PrintConfig {
+		if err := variables.WriteConfig(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
 
 	// Initialize logger
-	log := logger.NewLogger(logger.Config{
-		Format:    variables.LogFormat,
-		Level:     variables.LogLevel,
-		AddSource: variables.LogSource,
-	})
+	logConfig := logger.Config{
+		Format:         variables.LogFormat,
+		Level:          variables.LogLevel,
+		AddSource:      variables.LogSource,
+		TimeFormat:     variables.LogTimeFormat,
+		UTC:            variables.LogTimeUTC,
+		AddCaller:      variables.LogCaller,
+		AddGoroutineID: variables.LogGoroutineID,
+		RedactKeys:     variables.LogRedactKeys,
+		Output:         variables.LogOutput,
+		File: logger.FileConfig{
+			Path:           variables.LogFilePath,
+			MaxSize:        variables.LogFileMaxSize,
+			RotateInterval: variables.LogFileRotateInterval,
+			MaxBackups:     variables.LogFileMaxBackups,
+			MaxAge:         variables.LogFileMaxAge,
+		},
+		ErrorsSubject: variables.LogErrorsSubject,
+	}
+	if variables.LogSamplingInitial > 0 {
+		logConfig.Sampling = &logger.SamplingConfig{
+			Initial:    variables.LogSamplingInitial,
+			Thereafter: variables.LogSamplingThereafter,
+			Interval:   variables.LogSamplingInterval,
+		}
+	}
+	log := logger.NewLogger(logConfig)
+	defer log.Close()
 	slog.SetDefault(log.SlogLogger())
+	log.Debug("Configuration loaded", "variables", variables)
+
+	// Initialize tracing, a no-op unless an OTLP endpoint is configured
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Config{
+		ServiceName: serviceName,
+		Environment: variables.Env,
+		Endpoint:    variables.OTLPEndpoint,
+		Insecure:    variables.OTLPInsecure,
+		SampleRatio: variables.TracingSampleRate,
+	})
+	if err != nil {
+		log.Error("Could not initialize tracing", "error", err)
+		return 1
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Error("Could not flush traces", "error", err)
+		}
+	}()
+
+	// Initialize metrics, only exposed when enabled
+	appMetrics := metrics.New()
+
+	// Load the secrets that can be rotated without a restart
+	secrets, err := environment.NewSecrets(variables)
+	if err != nil {
+		log.Error("Could not load secrets", "error", err)
+		return 1
+	}
 
 	// Initialize NATS connection
-	natsConn := environment.MustInitNATSConn(variables)
+	natsConn := environment.MustInitNATSConn(variables, secrets, appMetrics)
 	defer natsConn.Close()
+	log.ConnectNATS(natsConn)
+
+	// Serve runtime log level changes
+	logLevelSubject := fmt.Sprintf("%s.%s.loglevel", variables.LogAdminSubject, serviceName)
+	if _, err := log.ServeLevelRequests(natsConn, logLevelSubject, variables.LogLevelTTL); err != nil {
+		log.Error("Could not serve log level requests", "error", err)
+		return 1
+	}
 
 	// Initialize service first
 	service := res.NewService(serviceName)
-	service.SetLogger(log)
+	service.SetLogger(log.Component("res"))
 	service.SetInChannelSize(variables.ServiceInChannelSize)
 	service.SetWorkerCount(variables.ServiceWorkerCount)
 
+	dbConn := environment.OpenPGSQLDB(variables, secrets)
+	store := store.NewStore(store.WithDB(dbConn), store.WithQueryObserver(appMetrics))
+
 	// Initialize health checker
 	healthChecker := health.New(
 		natsConn,
 		health.NewVersionInfo(variables.Env),
-		health.WithNATSCheck(natsConn),
-		health.WithInterval(variables.HealthCheckInterval),
-		health.WithTimeout(variables.HealthCheckTimeout),
-		health.WithSubject(variables.HealthCheckSubject),
-		health.WithServiceName(serviceName),
+		append(
+			optionalHealthChecks(variables, natsConn),
+			health.WithNATSCheck(natsConn),
+			health.WithSQLCheck(dbConn),
+			health.WithMigrationCheck(dbConn, migrationsTable),
+			health.WithSQLPoolStats("postgres", dbConn),
+			health.WithInterval(variables.HealthCheckInterval),
+			health.WithTimeout(variables.HealthCheckTimeout),
+			health.WithSubject(variables.HealthCheckSubject),
+			health.WithStatusTopic(variables.HealthCheckStatusTopic),
+			health.WithEventsTopic(variables.HealthCheckEventsTopic),
+			health.WithHistorySize(variables.HealthCheckHistorySize),
+			health.WithServiceName(serviceName),
+		)...,
 	)
 
-	dbConn := environment.MustInitPGSQLDB(variables)
-	store := store.NewStore(store.WithDB(dbConn))
+	appMetrics.RegisterActiveRooms(store.Rooms.CountActiveRooms)
+	appMetrics.RegisterHealth(healthChecker.GetHealth)
 
 	// Create server with all dependencies
-	srv := server.New(
+	options := []server.Option{
 		server.WithService(service),
 		server.WithLogger(log),
 		server.WithHealthChecker(healthChecker),
 		server.WithShutdownTimeout(variables.ShutdownTimeout),
+		server.WithDrainDelay(variables.ShutdownDrainDelay),
 		server.WithStore(store),
-	)
+		server.WithCursorCodec(pagination.NewCursorCodec(
+			pagination.WithSigningKey([]byte(variables.PaginationCursorKey)),
+		)),
+		server.WithPageSizePolicy(pagination.SizePolicy{
+			Default: variables.PaginationDefaultSize,
+			Min:     variables.PaginationMinSize,
+			Max:     variables.PaginationMaxSize,
+		}),
+	}
+	if variables.MetricsEnabled {
+		options = append(options,
+			server.WithMetrics(appMetrics),
+			server.WithMetricsHTTPAddr(fmt.Sprintf(":%d", variables.MetricsPort)),
+		)
+	}
+	if variables.HealthCheckHTTPPort != 0 {
+		options = append(options, server.WithHealthHTTPAddr(fmt.Sprintf(":%d", variables.HealthCheckHTTPPort)))
+	}
+	srv := server.New(options...)
 
 	// Setup context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
@@ -77,9 +198,65 @@ func main() {
 		cancel()
 	}()
 
+	// Connect to the DB in the background, the service staying unready until then and exiting if it gives up
+	dbReady := healthChecker.WaitFor("postgres")
+	startupErr := make(chan error, 1)
+	go func() {
+		if err := environment.WaitForPGSQLDB(ctx, dbConn, variables.PGConnectBackoff()); err != nil {
+			log.Error("Failed to connect to PGSQL DB", "error", err)
+			startupErr <- err
+			cancel()
+			return
+		}
+		log.Info("Connected to PGSQL DB")
+		dbReady()
+	}()
+
+	// Reload the configuration on SIGHUP
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	go func() {
+		for range hupChan {
+			reloadConfiguration(variables, secrets, log, healthChecker)
+		}
+	}()
+
 	// Start server
 	if err := srv.Start(ctx, natsConn); err != nil {
 		log.Error("Server error", "error", err)
-		os.Exit(1)
+		return 1
+	}
+
+	select {
+	case <-startupErr:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// optionalHealthChecks returns the configured non-critical health checks, which only degrade the service when failing
+func optionalHealthChecks(variables *environment.Variables, natsConn *nats.Conn) []health.Option {
+	var options []health.Option
+
+	if variables.HealthCheckJetStream {
+		options = append(options, health.WithJetStreamCheck(natsConn, variables.HealthCheckJetStreamStreams...))
+	}
+	if variables.HealthCheckDiskPath != "" {
+		options = append(options, health.WithDiskCheck(variables.HealthCheckDiskPath, variables.HealthCheckDiskMinFree))
+	}
+	if variables.HealthCheckMaxGoroutines != 0 {
+		options = append(options, health.WithGoroutineCheck(variables.HealthCheckMaxGoroutines))
+	}
+	if variables.HealthCheckMaxHeap != 0 {
+		options = append(options, health.WithHeapCheck(variables.HealthCheckMaxHeap))
+	}
+	if variables.HealthCheckResgateURL != "" {
+		options = append(options, health.WithResgateCheck(variables.HealthCheckResgateURL))
+	}
+
+	for i, option := range options {
+		options[i] = health.Optional(option)
 	}
+	return options
 }
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+
+	"github.com/Autherain/go_cyber/environment"
+	"github.com/Autherain/go_cyber/internal/health"
+	"github.com/Autherain/go_cyber/internal/logger"
+)
+
+// reloadConfiguration parses the configuration again and applies the secrets and the reloadable settings, reporting
+// the changed settings that require a restart. The process environment can't change, so the changes come from the
+// configuration file and the secret files.
+func reloadConfiguration(
+	variables *environment.Variables,
+	secrets *environment.Secrets,
+	log *logger.Logger,
+	healthChecker *health.HealthChecker,
+) {
+	reloaded, err := environment.Parse(os.Args[1:])
+	if err != nil {
+		log.Error("Could not reload configuration", "error", err)
+		return
+	}
+
+	if err := secrets.Update(reloaded); err != nil {
+		log.Error("Could not reload secrets", "error", err)
+		return
+	}
+
+	applied, restart := variables.Reload(reloaded)
+	log.Reconfigure(variables.LogLevel, variables.LogFormat)
+	healthChecker.SetInterval(variables.HealthCheckInterval)
+	healthChecker.SetTimeout(variables.HealthCheckTimeout)
+
+	log.Info("Reloaded configuration", "applied", applied)
+	if len(restart) > 0 {
+		log.Warn("Changed settings require a restart", "variables", restart)
+	}
+}
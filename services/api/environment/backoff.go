@@ -0,0 +1,61 @@
+package environment
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff configures the retries of an operation: the delays double from Initial up to Max, each randomized by up to
+// the Jitter fraction, until the operation succeeds or the Timeout expires.
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+	Jitter  float64
+	Timeout time.Duration // Zero retries until the context is done
+}
+
+// delay returns the randomized delay before the given retry, starting from 1.
+func (b Backoff) delay(retry int) time.Duration {
+	delay := b.Initial
+	for i := 1; i < retry && delay < b.Max; i++ {
+		delay *= 2
+	}
+	delay = min(delay, b.Max)
+
+	return delay + time.Duration(b.Jitter*(2*rand.Float64()-1)*float64(delay))
+}
+
+// retry calls the operation until it succeeds, notifying each failed attempt along with the delay before the next
+// one.
+func (b Backoff) retry(
+	ctx context.Context,
+	operation func(context.Context) error,
+	onRetry func(attempt int, delay time.Duration, err error),
+) error {
+	if b.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.Timeout)
+		defer cancel()
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := operation(ctx)
+		if err == nil {
+			return nil
+		}
+
+		delay := b.delay(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+		}
+		onRetry(attempt, delay, err)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package environment
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	variablePrefix     = "APP_"
+	configFileVariable = "APP_CONFIG_FILE"
+	envSeparator       = ","
+	maskedValue        = "******"
+)
+
+// layer maps APP_* variable names to their raw value, so that the configuration file, the environment and the command
+// line flags are all parsed and validated the same way.
+type layer map[string]string
+
+// variables lists the fields of Variables bound to an environment variable, in declaration order.
+func variables() []reflect.StructField {
+	var fields []reflect.StructField
+	for _, field := range reflect.VisibleFields(reflect.TypeFor[Variables]()) {
+		if variableTag(field) != "" {
+			fields = append(fields, field)
+		}
+	}
+
+	return fields
+}
+
+func variableTag(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("env"), ",")
+	return name
+}
+
+// isSecret tells whether the variable is a secret, which is masked whenever the configuration is printed or logged.
+func isSecret(field reflect.StructField) bool {
+	return field.Tag.Get("secret") == "true"
+}
+
+// flagName derives the command line flag of a variable, such as --log-level for APP_LOG_LEVEL.
+func flagName(variable string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(variable, variablePrefix), "_", "-"))
+}
+
+// flagValue records the flags set on the command line into the flags layer.
+type flagValue struct {
+	variable string
+	layer    layer
+	boolean  bool
+}
+
+func (f *flagValue) String() string   { return "" }
+func (f *flagValue) IsBoolFlag() bool { return f.boolean }
+
+func (f *flagValue) Set(value string) error {
+	f.layer[f.variable] = value
+	return nil
+}
+
+// parseFlags parses the command line into a layer, a flag being generated for each variable but the secrets, which
+// would be visible in the process list: their _FILE variable, if any, is the way to pass them instead.
+func parseFlags(args []string) (layer, bool, error) {
+	flags := make(layer)
+	fs := flag.NewFlagSet("api", flag.ContinueOnError)
+
+	printConfig := fs.Bool("print-config", false, "print the effective configuration, secrets masked, and exit")
+	for _, field := range variables() {
+		if isSecret(field) {
+			continue
+		}
+
+		variable := variableTag(field)
+		usage := "sets " + variable
+		if value, ok := field.Tag.Lookup("envDefault"); ok {
+			usage += fmt.Sprintf(" (default %q)", value)
+		}
+
+		value := &flagValue{variable: variable, layer: flags, boolean: field.Type.Kind() == reflect.Bool}
+		fs.Var(value, flagName(variable), usage)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, false, err
+	}
+
+	return flags, *printConfig, nil
+}
+
+// readConfigFile reads a YAML or TOML configuration file into a layer. Keys are the variable names without their
+// APP_ prefix, in any case, and nested tables are joined with underscores: both "log_level: debug" and
+// "log: {level: debug}" set APP_LOG_LEVEL.
+func readConfigFile(path string) (layer, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read config file: %w", err)
+	}
+
+	var settings map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &settings)
+	case ".toml":
+		err = toml.Unmarshal(content, &settings)
+	default:
+		return nil, fmt.Errorf("unsupported config file format %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse config file %s: %w", path, err)
+	}
+
+	known := make(map[string]bool)
+	for _, field := range variables() {
+		known[variableTag(field)] = true
+	}
+
+	file := make(layer)
+	if err := flattenSettings(file, known, "", settings); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	return file, nil
+}
+
+func flattenSettings(file layer, known map[string]bool, prefix string, settings map[string]any) error {
+	for key, value := range settings {
+		name := prefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(key))
+
+		switch value := value.(type) {
+		case map[string]any:
+			if err := flattenSettings(file, known, name+"_", value); err != nil {
+				return err
+			}
+			continue
+		case []any:
+			items := make([]string, 0, len(value))
+			for _, item := range value {
+				items = append(items, fmt.Sprint(item))
+			}
+			file[variablePrefix+name] = strings.Join(items, envSeparator)
+		case nil:
+			file[variablePrefix+name] = ""
+		default:
+			file[variablePrefix+name] = fmt.Sprint(value)
+		}
+
+		if !known[variablePrefix+name] {
+			return fmt.Errorf("unknown setting %q", strings.ToLower(name))
+		}
+	}
+
+	return nil
+}
+
+// environmentLayer returns the APP_* variables of the process environment.
+func environmentLayer() layer {
+	environment := make(layer)
+	for _, variable := range os.Environ() {
+		if name, value, _ := strings.Cut(variable, "="); strings.HasPrefix(name, variablePrefix) {
+			environment[name] = value
+		}
+	}
+
+	return environment
+}
+
+// mergeLayers overlays the layers in order of increasing precedence.
+func mergeLayers(layers ...layer) layer {
+	merged := make(layer)
+	for _, l := range layers {
+		maps.Copy(merged, l)
+	}
+
+	return merged
+}
+
+// Reload copies the reloadable settings changed in the other configuration, returning the variables applied and the
+// changed ones that require a restart.
+func (v *Variables) Reload(other *Variables) (applied, restart []string) {
+	current, next := reflect.ValueOf(v).Elem(), reflect.ValueOf(other).Elem()
+	for _, field := range variables() {
+		currentValue, nextValue := current.FieldByIndex(field.Index), next.FieldByIndex(field.Index)
+		if reflect.DeepEqual(currentValue.Interface(), nextValue.Interface()) {
+			continue
+		}
+
+		if field.Tag.Get("reload") == "true" {
+			currentValue.Set(nextValue)
+			applied = append(applied, variableTag(field))
+		} else {
+			restart = append(restart, variableTag(field))
+		}
+	}
+
+	return applied, restart
+}
+
+// WriteConfig writes the effective configuration as APP_* assignments, masking the secrets.
+func (v *Variables) WriteConfig(w io.Writer) error {
+	value := reflect.ValueOf(v).Elem()
+	for _, field := range variables() {
+		setting := formatSetting(value.FieldByIndex(field.Index))
+		if isSecret(field) && setting != "" {
+			setting = maskedValue
+		}
+
+		if _, err := fmt.Fprintf(w, "%s=%s\n", variableTag(field), setting); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func formatSetting(value reflect.Value) string {
+	if value.Kind() == reflect.Slice {
+		items := make([]string, 0, value.Len())
+		for i := range value.Len() {
+			items = append(items, fmt.Sprint(value.Index(i).Interface()))
+		}
+		return strings.Join(items, envSeparator)
+	}
+
+	return fmt.Sprint(value.Interface())
+}
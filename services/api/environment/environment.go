@@ -1,10 +1,13 @@
 package environment
 
 import (
+	"cmp"
+	"context"
 	"database/sql"
 	"fmt"
 	"log/slog"
 	"os"
+	"reflect"
 	"time"
 
 	"github.com/Autherain/go_cyber/internal/logger"
@@ -13,57 +16,191 @@ import (
 	"github.com/nats-io/nats.go"
 )
 
-// Variables represents the environment variables used by the application.
+// Variables represents the environment variables used by the application. The ones tagged reload can be applied
+// without a restart.
 type Variables struct {
+	// Configuration file, in YAML or TOML, overlaid by the environment and the command line flags
+	ConfigFile string `env:"APP_CONFIG_FILE"`
+	// Set by the --print-config flag
+	PrintConfig bool
+
 	// NATS Configuration
 	NATSURL string `env:"APP_NATS_URL" envDefault:"nats://localhost:4222"`
+	// NATS authentication and TLS files, the client certificate being reloaded on SIGHUP
+	NATSCredsFile    string `env:"APP_NATS_CREDS_FILE"`
+	NATSNkeySeedFile string `env:"APP_NATS_NKEY_SEED_FILE"`
+	NATSTLSCert      string `env:"APP_NATS_TLS_CERT" reload:"true"`
+	NATSTLSKey       string `env:"APP_NATS_TLS_KEY" reload:"true"`
+	NATSTLSCA        string `env:"APP_NATS_TLS_CA"`
 
 	// Service Configuration
 	ServiceName          string        `env:"APP_SERVICE_NAME" envDefault:"myapp"`
 	ServiceInChannelSize int           `env:"APP_SERVICE_IN_CHANNEL_SIZE" envDefault:"1024"`
 	ServiceWorkerCount   int           `env:"APP_SERVICE_WORKER_COUNT" envDefault:"32"`
 	ShutdownTimeout      time.Duration `env:"APP_SHUTDOWN_TIMEOUT" envDefault:"5s"`
+	// Time during which the service reports unready before shutting down, for the probes to notice
+	ShutdownDrainDelay time.Duration `env:"APP_SHUTDOWN_DRAIN_DELAY" envDefault:"5s"`
 
 	// Logger Configuration
-	LogFormat logger.Format   `env:"APP_LOG_FORMAT" envDefault:"json"`
-	LogLevel  logger.LogLevel `env:"APP_LOG_LEVEL" envDefault:"info"`
+	LogFormat logger.Format   `env:"APP_LOG_FORMAT" envDefault:"json" reload:"true"`
+	LogLevel  logger.LogLevel `env:"APP_LOG_LEVEL" envDefault:"info" reload:"true"`
 	LogSource bool            `env:"APP_LOG_SOURCE" envDefault:"false"`
+	// Record time layout (rfc3339, rfc3339nano or unixmilli), and debugging attributes
+	LogTimeFormat  logger.TimeFormat `env:"APP_LOG_TIME_FORMAT" envDefault:"rfc3339nano"`
+	LogTimeUTC     bool              `env:"APP_LOG_TIME_UTC" envDefault:"true"`
+	LogCaller      bool              `env:"APP_LOG_CALLER" envDefault:"false"`
+	LogGoroutineID bool              `env:"APP_LOG_GOROUTINE_ID" envDefault:"false"`
+	// Attribute keys redacted from the logs, in addition to the secrets, ciphertexts and client addresses
+	LogRedactKeys []string `env:"APP_LOG_REDACT_KEYS" envSeparator:","`
+
+	// Log sampling per level and message, disabled when the initial count is 0
+	LogSamplingInitial    int           `env:"APP_LOG_SAMPLING_INITIAL" envDefault:"0"`
+	LogSamplingThereafter int           `env:"APP_LOG_SAMPLING_THEREAFTER" envDefault:"100"`
+	LogSamplingInterval   time.Duration `env:"APP_LOG_SAMPLING_INTERVAL" envDefault:"1s"`
+
+	// Log outputs, the file being rotated by size and age, and errors being also published to NATS when a subject is set
+	LogOutput             logger.Output `env:"APP_LOG_OUTPUT" envDefault:"stdout"`
+	LogFilePath           string        `env:"APP_LOG_FILE_PATH" envDefault:"logs/api.log"`
+	LogFileMaxSize        int64         `env:"APP_LOG_FILE_MAX_SIZE" envDefault:"104857600"` // In bytes
+	LogFileRotateInterval time.Duration `env:"APP_LOG_FILE_ROTATE_INTERVAL" envDefault:"24h"`
+	LogFileMaxBackups     int           `env:"APP_LOG_FILE_MAX_BACKUPS" envDefault:"7"`
+	LogFileMaxAge         time.Duration `env:"APP_LOG_FILE_MAX_AGE" envDefault:"168h"`
+	LogErrorsSubject      string        `env:"APP_LOG_ERRORS_SUBJECT"`
+
+	// Runtime log level changes, requested on <subject>.<service>.loglevel and reverted after the TTL
+	LogAdminSubject string        `env:"APP_LOG_ADMIN_SUBJECT" envDefault:"admin"`
+	LogLevelTTL     time.Duration `env:"APP_LOG_LEVEL_TTL" envDefault:"15m"`
 
 	// Health Check Configuration
 	HealthCheckEnabled     bool          `env:"APP_HEALTH_CHECK_ENABLED" envDefault:"true"`
-	HealthCheckInterval    time.Duration `env:"APP_HEALTH_CHECK_INTERVAL" envDefault:"10s"`
-	HealthCheckTimeout     time.Duration `env:"APP_HEALTH_CHECK_TIMEOUT" envDefault:"5s"`
+	HealthCheckInterval    time.Duration `env:"APP_HEALTH_CHECK_INTERVAL" envDefault:"10s" reload:"true"`
+	HealthCheckTimeout     time.Duration `env:"APP_HEALTH_CHECK_TIMEOUT" envDefault:"5s" reload:"true"`
 	HealthCheckSubject     string        `env:"APP_HEALTH_CHECK_SUBJECT" envDefault:"health"`
 	HealthCheckStatusTopic string        `env:"APP_HEALTH_CHECK_STATUS_TOPIC" envDefault:"health.status"`
+	HealthCheckEventsTopic string        `env:"APP_HEALTH_CHECK_EVENTS_TOPIC" envDefault:"health.events"`
+	HealthCheckHistorySize int           `env:"APP_HEALTH_CHECK_HISTORY_SIZE" envDefault:"10"`
+	HealthCheckHTTPPort    int           `env:"APP_HEALTH_CHECK_HTTP_PORT" envDefault:"0"` // 0 disables the HTTP probes
+
+	// Optional Health Checks, disabled when left empty or zero
+	HealthCheckJetStream        bool     `env:"APP_HEALTH_CHECK_JETSTREAM" envDefault:"false"`
+	HealthCheckJetStreamStreams []string `env:"APP_HEALTH_CHECK_JETSTREAM_STREAMS" envSeparator:","`
+	HealthCheckDiskPath         string   `env:"APP_HEALTH_CHECK_DISK_PATH"`
+	HealthCheckDiskMinFree      uint64   `env:"APP_HEALTH_CHECK_DISK_MIN_FREE"` // In bytes
+	HealthCheckMaxGoroutines    uint64   `env:"APP_HEALTH_CHECK_MAX_GOROUTINES"`
+	HealthCheckMaxHeap          uint64   `env:"APP_HEALTH_CHECK_MAX_HEAP"` // In bytes
+	HealthCheckResgateURL       string   `env:"APP_HEALTH_CHECK_RESGATE_URL"`
+
+	// Metrics Configuration, served on their own port unless it is the one of the HTTP health probes
+	MetricsEnabled bool `env:"APP_METRICS_ENABLED" envDefault:"true"`
+	MetricsPort    int  `env:"APP_METRICS_PORT" envDefault:"9090"`
+
+	// Tracing Configuration, tracing stays a no-op without an OTLP endpoint
+	OTLPEndpoint      string  `env:"APP_OTLP_ENDPOINT"`
+	OTLPInsecure      bool    `env:"APP_OTLP_INSECURE" envDefault:"false"`
+	TracingSampleRate float64 `env:"APP_TRACING_SAMPLE_RATE" envDefault:"1"`
+
+	// Pagination Configuration
+	PaginationCursorKey     string `env:"APP_PAGINATION_CURSOR_KEY" secret:"true"`
+	PaginationCursorKeyFile string `env:"APP_PAGINATION_CURSOR_KEY_FILE"` // Content replaces the cursor key
+	PaginationDefaultSize   int    `env:"APP_PAGINATION_DEFAULT_SIZE" envDefault:"25"`
+	PaginationMinSize       int    `env:"APP_PAGINATION_MIN_SIZE" envDefault:"1"`
+	PaginationMaxSize       int    `env:"APP_PAGINATION_MAX_SIZE" envDefault:"100"`
 
 	// PostgreSQL Configuration
 	PGHost     string `env:"APP_PG_HOST" envDefault:"localhost"`
 	PGPort     int    `env:"APP_PG_PORT" envDefault:"5432"`
 	PGUser     string `env:"APP_PG_USER" envDefault:"api"`
-	PGPassword string `env:"APP_PG_PASSWORD" envDefault:"api"`
+	PGPassword string `env:"APP_PG_PASSWORD" envDefault:"api" secret:"true" reload:"true"`
 	PGDatabase string `env:"APP_PG_DATABASE" envDefault:"api"`
 	PGSSLMode  string `env:"APP_PG_SSL_MODE" envDefault:"disable"`
+	// Secret and TLS files, the password being re-read on SIGHUP and the certificates on each connection
+	PGPasswordFile string `env:"APP_PG_PASSWORD_FILE" reload:"true"` // Content replaces the password
+	PGSSLCert      string `env:"APP_PG_SSL_CERT"`
+	PGSSLKey       string `env:"APP_PG_SSL_KEY"`
+	PGSSLRootCert  string `env:"APP_PG_SSL_ROOT_CERT"`
+	// Full connection string, key/value or URL, overriding the settings above
+	PGDSN     string `env:"APP_PG_DSN" secret:"true" reload:"true"`
+	PGDSNFile string `env:"APP_PG_DSN_FILE" reload:"true"` // Content replaces the connection string
+	// Connection pool and session settings, 0 leaving the pool and the statements unbounded
+	PGMaxOpenConns     int           `env:"APP_PG_MAX_OPEN_CONNS" envDefault:"25"`
+	PGMaxIdleConns     int           `env:"APP_PG_MAX_IDLE_CONNS" envDefault:"5"`
+	PGConnMaxLifetime  time.Duration `env:"APP_PG_CONN_MAX_LIFETIME" envDefault:"30m"`
+	PGConnMaxIdleTime  time.Duration `env:"APP_PG_CONN_MAX_IDLE_TIME" envDefault:"5m"`
+	PGStatementTimeout time.Duration `env:"APP_PG_STATEMENT_TIMEOUT" envDefault:"30s"`
+	PGApplicationName  string        `env:"APP_PG_APPLICATION_NAME" envDefault:"api"`
+	// Startup connection retries, the service staying unready until the DB is reachable and exiting after the timeout
+	PGConnectInitialBackoff time.Duration `env:"APP_PG_CONNECT_INITIAL_BACKOFF" envDefault:"500ms"`
+	PGConnectMaxBackoff     time.Duration `env:"APP_PG_CONNECT_MAX_BACKOFF" envDefault:"15s"`
+	PGConnectJitter         float64       `env:"APP_PG_CONNECT_JITTER" envDefault:"0.2"`
+	PGConnectTimeout        time.Duration `env:"APP_PG_CONNECT_TIMEOUT" envDefault:"2m"`
 
 	// Version Information
 	Env string `env:"APP_ENV" envDefault:"development"`
 }
 
-// Parse environment variables.
-func Parse() *Variables {
+// Parse the configuration from, by increasing precedence, the defaults, the configuration file (if any), the
+// environment (including the .env file) and the command line flags. It returns a *ValidationError listing every
+// invalid variable.
+func Parse(args []string) (*Variables, error) {
 	if err := godotenv.Load(); err != nil && !os.IsNotExist(err) {
-		panic(fmt.Errorf("error loading .env file: %w", err))
+		return nil, fmt.Errorf("error loading .env file: %w", err)
+	}
+
+	flags, printConfig, err := parseFlags(args)
+	if err != nil {
+		return nil, err
 	}
 
-	cfg := &Variables{}
-	if err := env.Parse(cfg); err != nil {
-		panic(fmt.Errorf("could not parse environment variables: %w", err))
+	environment := environmentLayer()
+	layers := []layer{environment, flags}
+	if path := cmp.Or(flags[configFileVariable], environment[configFileVariable]); path != "" {
+		file, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		layers = append([]layer{file}, layers...)
+	}
+
+	cfg := &Variables{PrintConfig: printConfig}
+	parseErr := env.ParseWithOptions(cfg, env.Options{Environment: mergeLayers(layers...)})
+	secretErrs := cfg.readSecretFiles()
+	if err := cfg.validate(parseErr, secretErrs); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// LogValue logs each variable under its field name, masking the secrets.
+func (v *Variables) LogValue() slog.Value {
+	value := reflect.ValueOf(v).Elem()
+	attrs := make([]slog.Attr, 0, value.NumField())
+	for i := range value.NumField() {
+		field, fieldValue := value.Type().Field(i), value.Field(i)
+		if isSecret(field) && !fieldValue.IsZero() {
+			attrs = append(attrs, slog.String(field.Name, maskedValue))
+			continue
+		}
+
+		attrs = append(attrs, slog.Any(field.Name, fieldValue.Interface()))
 	}
 
-	return cfg
+	return slog.GroupValue(attrs...)
+}
+
+// ConnectionObserver is notified of the NATS connection losses and reconnections
+type ConnectionObserver interface {
+	NATSDisconnected(err error)
+	NATSReconnected()
 }
 
 // MustInitNATSConn initializes a NATS connection with retry logic
-func MustInitNATSConn(variables *Variables) *nats.Conn {
+func MustInitNATSConn(variables *Variables, secrets *Secrets, observers ...ConnectionObserver) *nats.Conn {
+	authOpts, err := secrets.natsAuthOptions(variables)
+	if err != nil {
+		panic(fmt.Errorf("could not configure NATS authentication: %w", err))
+	}
+
 	opts := []nats.Option{
 		nats.Name(variables.ServiceName),
 		nats.RetryOnFailedConnect(true),
@@ -73,13 +210,19 @@ func MustInitNATSConn(variables *Variables) *nats.Conn {
 			if err != nil {
 				slog.Error("NATS disconnected", "error", err)
 			}
+			for _, observer := range observers {
+				observer.NATSDisconnected(err)
+			}
 		}),
 		nats.ReconnectHandler(func(nc *nats.Conn) {
 			slog.Info("NATS reconnected", "url", nc.ConnectedUrl())
+			for _, observer := range observers {
+				observer.NATSReconnected()
+			}
 		}),
 	}
 
-	conn, err := nats.Connect(variables.NATSURL, opts...)
+	conn, err := nats.Connect(variables.NATSURL, append(opts, authOpts...)...)
 	if err != nil {
 		panic(fmt.Errorf("could not connect to NATS: %w", err))
 	}
@@ -87,25 +230,45 @@ func MustInitNATSConn(variables *Variables) *nats.Conn {
 	return conn
 }
 
-func MustInitPGSQLDB(variables *Variables) *sql.DB {
-	connStr := fmt.Sprintf(
-		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		variables.PGHost,
-		variables.PGPort,
-		variables.PGUser,
-		variables.PGPassword,
-		variables.PGDatabase,
-		variables.PGSSLMode,
-	)
-	db, err := sql.Open("postgres", connStr)
-	if err != nil {
-		panic(fmt.Errorf("could not connect to PGSQL DB: %w", err))
+// OpenPGSQLDB opens the Postgres DB with the configured pool, whose connections use the current password of the
+// secrets. No connection is made until the DB is used, see WaitForPGSQLDB.
+func OpenPGSQLDB(variables *Variables, secrets *Secrets) *sql.DB {
+	db := sql.OpenDB(&pgConnector{variables: variables, secrets: secrets})
+	db.SetMaxOpenConns(variables.PGMaxOpenConns)
+	db.SetMaxIdleConns(variables.PGMaxIdleConns)
+	db.SetConnMaxLifetime(variables.PGConnMaxLifetime)
+	db.SetConnMaxIdleTime(variables.PGConnMaxIdleTime)
+
+	return db
+}
+
+// pgPingTimeout bounds each connection attempt, so that an unresponsive host does not use up the whole backoff timeout.
+const pgPingTimeout = 5 * time.Second
+
+// PGConnectBackoff returns the backoff of the startup connection to the PGSQL DB.
+func (v *Variables) PGConnectBackoff() Backoff {
+	return Backoff{
+		Initial: v.PGConnectInitialBackoff,
+		Max:     v.PGConnectMaxBackoff,
+		Jitter:  v.PGConnectJitter,
+		Timeout: v.PGConnectTimeout,
 	}
+}
 
-	// Vérification basique de la connexion
-	if err = db.Ping(); err != nil {
-		panic(fmt.Errorf("ping failure PGSQL DB: %w", err))
+// WaitForPGSQLDB pings the DB until it is reachable, retrying with the backoff.
+func WaitForPGSQLDB(ctx context.Context, db *sql.DB, backoff Backoff) error {
+	ping := func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, pgPingTimeout)
+		defer cancel()
+		return db.PingContext(ctx)
 	}
 
-	return db
+	err := backoff.retry(ctx, ping, func(attempt int, delay time.Duration, err error) {
+		slog.Warn("PGSQL DB unreachable, retrying", "attempt", attempt, "retryIn", delay, "error", err)
+	})
+	if err != nil {
+		return fmt.Errorf("could not reach PGSQL DB: %w", err)
+	}
+
+	return nil
 }
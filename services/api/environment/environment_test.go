@@ -0,0 +1,35 @@
+package environment_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/Autherain/go_cyber/environment"
+)
+
+func TestSecretsMasked(t *testing.T) {
+	variables := &environment.Variables{
+		PGPassword:          "pg-password-sentinel",
+		PGDSN:               "postgres://api:dsn-password-sentinel@db:5432/api",
+		PaginationCursorKey: "cursor-key-sentinel",
+	}
+
+	var logged, printed bytes.Buffer
+	slog.New(slog.NewJSONHandler(&logged, nil)).Info("Configuration loaded", "config", variables)
+	if err := variables.WriteConfig(&printed); err != nil {
+		t.Fatalf("WriteConfig() error = %v", err)
+	}
+
+	for name, output := range map[string]string{"LogValue": logged.String(), "WriteConfig": printed.String()} {
+		if !strings.Contains(output, "******") {
+			t.Errorf("%s: no masked value:\n%s", name, output)
+		}
+		for _, secret := range []string{"pg-password-sentinel", "dsn-password-sentinel", "cursor-key-sentinel"} {
+			if strings.Contains(output, secret) {
+				t.Errorf("%s: %q leaked:\n%s", name, secret, output)
+			}
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package environment
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
+)
+
+// readSecretFiles replaces the secrets configured with a _FILE variable by the content of the file, returning the
+// errors keyed by variable.
+func (v *Variables) readSecretFiles() map[string]error {
+	errs := make(map[string]error)
+
+	for _, secret := range []struct {
+		variable string
+		path     string
+		value    *string
+	}{
+		{"APP_PG_PASSWORD_FILE", v.PGPasswordFile, &v.PGPassword},
+		{"APP_PAGINATION_CURSOR_KEY_FILE", v.PaginationCursorKeyFile, &v.PaginationCursorKey},
+		{"APP_PG_DSN_FILE", v.PGDSNFile, &v.PGDSN},
+	} {
+		if secret.path == "" {
+			continue
+		}
+
+		content, err := os.ReadFile(secret.path)
+		if err != nil {
+			errs[secret.variable] = err
+			continue
+		}
+		*secret.value = strings.TrimRight(string(content), "\r\n")
+	}
+
+	return errs
+}
+
+// Secrets holds the secrets that can be rotated without a restart, which Update replaces after the configuration is
+// parsed again. New connections use the current secrets. Files read by the NATS and Postgres clients on each
+// connection, such as the NATS credentials or the Postgres client certificates, need no update.
+type Secrets struct {
+	pgPassword atomic.Pointer[string]
+	pgDSN      atomic.Pointer[string]
+	natsCert   atomic.Pointer[tls.Certificate]
+}
+
+// NewSecrets loads the secrets of the configuration.
+func NewSecrets(variables *Variables) (*Secrets, error) {
+	secrets := &Secrets{}
+	if err := secrets.Update(variables); err != nil {
+		return nil, err
+	}
+
+	return secrets, nil
+}
+
+// Update replaces the secrets with the ones of the configuration, keeping the current ones on error.
+func (s *Secrets) Update(variables *Variables) error {
+	var cert *tls.Certificate
+	if variables.NATSTLSCert != "" {
+		loaded, err := tls.LoadX509KeyPair(variables.NATSTLSCert, variables.NATSTLSKey)
+		if err != nil {
+			return fmt.Errorf("could not load NATS client certificate: %w", err)
+		}
+		cert = &loaded
+	}
+
+	password, dsn := variables.PGPassword, variables.PGDSN
+	s.pgPassword.Store(&password)
+	s.pgDSN.Store(&dsn)
+	s.natsCert.Store(cert)
+
+	return nil
+}
+
+// natsAuthOptions returns the authentication and TLS options of the NATS connection.
+func (s *Secrets) natsAuthOptions(variables *Variables) ([]nats.Option, error) {
+	var opts []nats.Option
+
+	if variables.NATSCredsFile != "" {
+		// The credentials file is read on each connection
+		opts = append(opts, nats.UserCredentials(variables.NATSCredsFile))
+	}
+
+	if variables.NATSNkeySeedFile != "" {
+		option, err := nkeySeedOption(variables.NATSNkeySeedFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, option)
+	}
+
+	if variables.NATSTLSCert != "" || variables.NATSTLSCA != "" {
+		config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+		if variables.NATSTLSCA != "" {
+			ca, err := os.ReadFile(variables.NATSTLSCA)
+			if err != nil {
+				return nil, fmt.Errorf("could not read NATS CA: %w", err)
+			}
+
+			config.RootCAs = x509.NewCertPool()
+			if !config.RootCAs.AppendCertsFromPEM(ca) {
+				return nil, errors.New("could not parse NATS CA")
+			}
+		}
+
+		if variables.NATSTLSCert != "" {
+			config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+				return s.natsCert.Load(), nil
+			}
+		}
+
+		opts = append(opts, nats.Secure(config))
+	}
+
+	return opts, nil
+}
+
+// nkeySeedOption authenticates with the nkey of the seed file, which is read again to sign each connection nonce so
+// that a seed rewritten for the same key is picked up.
+func nkeySeedOption(path string) (nats.Option, error) {
+	keyPair, err := readNkeySeed(path)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := keyPair.PublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("invalid nkey seed: %w", err)
+	}
+
+	return nats.Nkey(publicKey, func(nonce []byte) ([]byte, error) {
+		keyPair, err := readNkeySeed(path)
+		if err != nil {
+			return nil, err
+		}
+		defer keyPair.Wipe()
+
+		return keyPair.Sign(nonce)
+	}), nil
+}
+
+func readNkeySeed(path string) (nkeys.KeyPair, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read nkey seed: %w", err)
+	}
+
+	keyPair, err := nkeys.FromSeed([]byte(strings.TrimSpace(string(content))))
+	if err != nil {
+		return nil, fmt.Errorf("invalid nkey seed: %w", err)
+	}
+
+	return keyPair, nil
+}
+
+// pgConnector opens the Postgres connections with the current password or connection string.
+type pgConnector struct {
+	variables *Variables
+	secrets   *Secrets
+}
+
+func (c *pgConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	connString, err := pgConnString(c.variables, *c.secrets.pgPassword.Load(), *c.secrets.pgDSN.Load())
+	if err != nil {
+		return nil, err
+	}
+
+	connector, err := pq.NewConnector(connString)
+	if err != nil {
+		return nil, err
+	}
+
+	return connector.Connect(ctx)
+}
+
+func (c *pgConnector) Driver() driver.Driver { return &pq.Driver{} }
+
+// pgConnString builds a key/value connection string, leaving out the empty values. The session settings come first,
+// so that a full connection string, which comes last, overrides them along with every other setting.
+func pgConnString(variables *Variables, password, dsn string) (string, error) {
+	params := []struct{ key, value string }{
+		{"application_name", variables.PGApplicationName},
+		{"statement_timeout", pgMilliseconds(variables.PGStatementTimeout)},
+	}
+	if dsn == "" {
+		params = append(params, []struct{ key, value string }{
+			{"host", variables.PGHost},
+			{"port", fmt.Sprint(variables.PGPort)},
+			{"user", variables.PGUser},
+			{"password", password},
+			{"dbname", variables.PGDatabase},
+			{"sslmode", variables.PGSSLMode},
+			{"sslcert", variables.PGSSLCert},
+			{"sslkey", variables.PGSSLKey},
+			{"sslrootcert", variables.PGSSLRootCert},
+		}...)
+	}
+
+	pairs := make([]string, 0, len(params)+1)
+	for _, param := range params {
+		if param.value != "" {
+			pairs = append(pairs, param.key+"="+quotePGValue(param.value))
+		}
+	}
+
+	if dsn != "" {
+		dsn, err := parsePGDSN(dsn)
+		if err != nil {
+			return "", err
+		}
+		pairs = append(pairs, dsn)
+	}
+
+	return strings.Join(pairs, " "), nil
+}
+
+// parsePGDSN converts a URL connection string to the key/value format.
+func parsePGDSN(dsn string) (string, error) {
+	if !strings.HasPrefix(dsn, "postgres://") && !strings.HasPrefix(dsn, "postgresql://") {
+		return dsn, nil
+	}
+
+	converted, err := pq.ParseURL(dsn)
+	if err != nil {
+		// The error could quote the credentials of the URL
+		return "", errors.New("invalid Postgres connection URL")
+	}
+
+	return converted, nil
+}
+
+// pgMilliseconds formats a duration as a Postgres setting in milliseconds, leaving out zero.
+func pgMilliseconds(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+
+	return fmt.Sprint(d.Milliseconds())
+}
+
+// quotePGValue quotes a connection string value, which may hold spaces or quotes once read from a file.
+func quotePGValue(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
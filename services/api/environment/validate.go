@@ -0,0 +1,171 @@
+package environment
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/Autherain/go_cyber/internal/logger"
+	"github.com/Autherain/go_cyber/internal/validator"
+	"github.com/caarlos0/env/v8"
+)
+
+var pgSSLModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// ValidationError lists the invalid environment variables along with their problem.
+type ValidationError struct {
+	Errors map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var b strings.Builder
+	b.WriteString("invalid environment configuration:")
+	for _, name := range names {
+		fmt.Fprintf(&b, "\n  %s: %s", name, e.Errors[name])
+	}
+
+	return b.String()
+}
+
+// validate checks the variables, along with the errors reported while parsing them and reading their secret files
+// (keyed by variable), and returns a *ValidationError listing every problem found.
+func (v *Variables) validate(parseErr error, secretErrs map[string]error) error {
+	checks := validator.New()
+
+	var aggregate env.AggregateError
+	if errors.As(parseErr, &aggregate) {
+		for _, err := range aggregate.Errors {
+			var parse env.ParseError
+			if errors.As(err, &parse) {
+				checks.AddError(variableName(parse.Name), parse.Err.Error())
+			} else {
+				checks.AddError("environment", err.Error())
+			}
+		}
+	} else if parseErr != nil {
+		checks.AddError("environment", parseErr.Error())
+	}
+
+	for variable, err := range secretErrs {
+		checks.AddError(variable, err.Error())
+	}
+
+	checks.Check(v.NATSURL != "", "APP_NATS_URL", "must be provided")
+	checkFile(checks, "APP_NATS_CREDS_FILE", v.NATSCredsFile)
+	checkFile(checks, "APP_NATS_NKEY_SEED_FILE", v.NATSNkeySeedFile)
+	checkFile(checks, "APP_NATS_TLS_CERT", v.NATSTLSCert)
+	checkFile(checks, "APP_NATS_TLS_KEY", v.NATSTLSKey)
+	checkFile(checks, "APP_NATS_TLS_CA", v.NATSTLSCA)
+	checks.Check((v.NATSTLSCert == "") == (v.NATSTLSKey == ""), "APP_NATS_TLS_KEY",
+		"must be provided along with APP_NATS_TLS_CERT")
+	checks.Check(v.ServiceInChannelSize > 0, "APP_SERVICE_IN_CHANNEL_SIZE", "must be greater than 0")
+	checks.Check(v.ServiceWorkerCount > 0, "APP_SERVICE_WORKER_COUNT", "must be greater than 0")
+	checks.Check(v.ShutdownTimeout > 0, "APP_SHUTDOWN_TIMEOUT", "must be greater than 0")
+	checks.Check(v.ShutdownDrainDelay >= 0, "APP_SHUTDOWN_DRAIN_DELAY", "must not be negative")
+
+	checks.Check(v.LogLevelTTL >= 0, "APP_LOG_LEVEL_TTL", "must not be negative")
+	checks.Check(v.LogSamplingInitial >= 0, "APP_LOG_SAMPLING_INITIAL", "must not be negative")
+	checks.Check(v.LogSamplingThereafter >= 0, "APP_LOG_SAMPLING_THEREAFTER", "must not be negative")
+	checks.Check(v.LogSamplingInitial == 0 || v.LogSamplingInterval > 0, "APP_LOG_SAMPLING_INTERVAL",
+		"must be greater than 0 when sampling")
+	checks.Check(v.LogOutput != logger.FileOutput || v.LogFilePath != "", "APP_LOG_FILE_PATH",
+		"must be provided for the file output")
+	checks.Check(v.LogFileMaxSize >= 0, "APP_LOG_FILE_MAX_SIZE", "must not be negative")
+	checks.Check(v.LogFileRotateInterval >= 0, "APP_LOG_FILE_ROTATE_INTERVAL", "must not be negative")
+	checks.Check(v.LogFileMaxBackups >= 0, "APP_LOG_FILE_MAX_BACKUPS", "must not be negative")
+	checks.Check(v.LogFileMaxAge >= 0, "APP_LOG_FILE_MAX_AGE", "must not be negative")
+
+	checks.Check(v.HealthCheckInterval > 0, "APP_HEALTH_CHECK_INTERVAL", "must be greater than 0")
+	checks.Check(v.HealthCheckTimeout > 0, "APP_HEALTH_CHECK_TIMEOUT", "must be greater than 0")
+	checks.Check(v.HealthCheckSubject != "", "APP_HEALTH_CHECK_SUBJECT", "must be provided")
+	checks.Check(v.HealthCheckHistorySize > 0, "APP_HEALTH_CHECK_HISTORY_SIZE", "must be greater than 0")
+	checks.Check(validPort(v.HealthCheckHTTPPort) || v.HealthCheckHTTPPort == 0, "APP_HEALTH_CHECK_HTTP_PORT",
+		"must be a port number, or 0 to disable")
+	checks.Check(v.HealthCheckResgateURL == "" || validURL(v.HealthCheckResgateURL), "APP_HEALTH_CHECK_RESGATE_URL",
+		"must be an absolute URL")
+
+	checks.Check(!v.MetricsEnabled || validPort(v.MetricsPort), "APP_METRICS_PORT",
+		"must be a port number when metrics are enabled")
+
+	checks.Check(v.TracingSampleRate >= 0 && v.TracingSampleRate <= 1, "APP_TRACING_SAMPLE_RATE",
+		"must be between 0 and 1")
+
+	checks.Check(v.PaginationMinSize > 0, "APP_PAGINATION_MIN_SIZE", "must be greater than 0")
+	checks.Check(v.PaginationMaxSize >= v.PaginationMinSize, "APP_PAGINATION_MAX_SIZE",
+		"must not be less than APP_PAGINATION_MIN_SIZE")
+	checks.Check(
+		v.PaginationDefaultSize >= v.PaginationMinSize && v.PaginationDefaultSize <= v.PaginationMaxSize,
+		"APP_PAGINATION_DEFAULT_SIZE", "must be between APP_PAGINATION_MIN_SIZE and APP_PAGINATION_MAX_SIZE",
+	)
+
+	checks.Check(v.PGHost != "", "APP_PG_HOST", "must be provided")
+	checks.Check(validPort(v.PGPort), "APP_PG_PORT", "must be a port number")
+	checks.Check(validator.PermittedValue(v.PGSSLMode, pgSSLModes...), "APP_PG_SSL_MODE",
+		fmt.Sprintf("must be one of %s", strings.Join(pgSSLModes, ", ")))
+	if v.PGDSN != "" {
+		_, err := parsePGDSN(v.PGDSN)
+		checks.Check(err == nil, "APP_PG_DSN", "must be a valid connection string")
+	}
+	checks.Check(v.PGMaxOpenConns >= 0, "APP_PG_MAX_OPEN_CONNS", "must not be negative")
+	checks.Check(v.PGMaxIdleConns >= 0, "APP_PG_MAX_IDLE_CONNS", "must not be negative")
+	checks.Check(v.PGMaxOpenConns == 0 || v.PGMaxIdleConns <= v.PGMaxOpenConns, "APP_PG_MAX_IDLE_CONNS",
+		"must not be greater than APP_PG_MAX_OPEN_CONNS")
+	checks.Check(v.PGConnMaxLifetime >= 0, "APP_PG_CONN_MAX_LIFETIME", "must not be negative")
+	checks.Check(v.PGConnMaxIdleTime >= 0, "APP_PG_CONN_MAX_IDLE_TIME", "must not be negative")
+	checks.Check(v.PGStatementTimeout >= 0, "APP_PG_STATEMENT_TIMEOUT", "must not be negative")
+	checks.Check(v.PGConnectInitialBackoff > 0, "APP_PG_CONNECT_INITIAL_BACKOFF", "must be greater than 0")
+	checks.Check(v.PGConnectMaxBackoff >= v.PGConnectInitialBackoff, "APP_PG_CONNECT_MAX_BACKOFF",
+		"must not be less than APP_PG_CONNECT_INITIAL_BACKOFF")
+	checks.Check(v.PGConnectJitter >= 0 && v.PGConnectJitter <= 1, "APP_PG_CONNECT_JITTER", "must be between 0 and 1")
+	checks.Check(v.PGConnectTimeout >= 0, "APP_PG_CONNECT_TIMEOUT", "must not be negative")
+	checkFile(checks, "APP_PG_SSL_CERT", v.PGSSLCert)
+	checkFile(checks, "APP_PG_SSL_KEY", v.PGSSLKey)
+	checkFile(checks, "APP_PG_SSL_ROOT_CERT", v.PGSSLRootCert)
+
+	if !checks.Valid() {
+		return &ValidationError{Errors: checks.Errors}
+	}
+
+	return nil
+}
+
+// variableName returns the environment variable of a Variables field.
+func variableName(field string) string {
+	if f, ok := reflect.TypeFor[Variables]().FieldByName(field); ok && variableTag(f) != "" {
+		return variableTag(f)
+	}
+
+	return field
+}
+
+// checkFile checks that the optional file of the variable exists.
+func checkFile(checks *validator.Validator, variable, path string) {
+	if path == "" {
+		return
+	}
+
+	info, err := os.Stat(path)
+	switch {
+	case err != nil:
+		checks.AddError(variable, err.Error())
+	case info.IsDir():
+		checks.AddError(variable, "must be a file")
+	}
+}
+
+func validPort(port int) bool { return port > 0 && port <= 65535 }
+
+func validURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
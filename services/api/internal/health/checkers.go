@@ -0,0 +1,150 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/metrics"
+
+	"github.com/nats-io/nats.go"
+)
+
+// WithJetStreamCheck adds a check of the JetStream account availability and of the given streams
+func WithJetStreamCheck(conn *nats.Conn, streams ...string) Option {
+	return WithCheck(&jetStreamChecker{conn: conn, streams: streams})
+}
+
+// WithDiskCheck adds a check failing when the free disk space of the filesystem holding path drops below minFree bytes
+func WithDiskCheck(path string, minFree uint64) Option {
+	return WithCheck(&diskChecker{path: path, minFree: minFree})
+}
+
+// WithGoroutineCheck adds a check failing when the number of live goroutines exceeds maxGoroutines
+func WithGoroutineCheck(maxGoroutines uint64) Option {
+	return WithCheck(&metricChecker{
+		name:      "goroutines",
+		metric:    "/sched/goroutines:goroutines",
+		threshold: maxGoroutines,
+	})
+}
+
+// WithHeapCheck adds a check failing when the memory occupied by live and unswept heap objects exceeds maxBytes
+func WithHeapCheck(maxBytes uint64) Option {
+	return WithCheck(&metricChecker{
+		name:      "heap",
+		metric:    "/memory/classes/heap/objects:bytes",
+		threshold: maxBytes,
+	})
+}
+
+// WithResgateCheck adds a check of the reachability of the Resgate HTTP API at the given URL
+func WithResgateCheck(url string) Option {
+	return WithCheck(&resgateChecker{url: url, client: http.DefaultClient})
+}
+
+type jetStreamChecker struct {
+	conn    *nats.Conn
+	streams []string
+}
+
+func (j *jetStreamChecker) Check(ctx context.Context) error {
+	js, err := j.conn.JetStream()
+	if err != nil {
+		return fmt.Errorf("JetStream context unavailable: %w", err)
+	}
+
+	if _, err := js.AccountInfo(nats.Context(ctx)); err != nil {
+		return fmt.Errorf("JetStream account unavailable: %w", err)
+	}
+
+	for _, stream := range j.streams {
+		if _, err := js.StreamInfo(stream, nats.Context(ctx)); err != nil {
+			return fmt.Errorf("JetStream stream %q unavailable: %w", stream, err)
+		}
+	}
+	return nil
+}
+
+func (j *jetStreamChecker) Name() string {
+	return "jetstream"
+}
+
+var _ Checker = (*jetStreamChecker)(nil)
+
+type diskChecker struct {
+	path    string
+	minFree uint64
+}
+
+func (d *diskChecker) Check(ctx context.Context) error {
+	free, err := freeDiskSpace(d.path)
+	if err != nil {
+		return fmt.Errorf("could not read free disk space of %s: %w", d.path, err)
+	}
+	if free < d.minFree {
+		return fmt.Errorf("%d bytes free on %s, below %d", free, d.path, d.minFree)
+	}
+	return nil
+}
+
+func (d *diskChecker) Name() string {
+	return "disk"
+}
+
+var _ Checker = (*diskChecker)(nil)
+
+// metricChecker fails when a runtime/metrics value goes above a threshold
+type metricChecker struct {
+	name      string
+	metric    string
+	threshold uint64
+}
+
+func (m *metricChecker) Check(ctx context.Context) error {
+	sample := []metrics.Sample{{Name: m.metric}}
+	metrics.Read(sample)
+
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return fmt.Errorf("metric %s is not supported", m.metric)
+	}
+	if value := sample[0].Value.Uint64(); value > m.threshold {
+		return fmt.Errorf("%s is %d, above %d", m.name, value, m.threshold)
+	}
+	return nil
+}
+
+func (m *metricChecker) Name() string {
+	return m.name
+}
+
+var _ Checker = (*metricChecker)(nil)
+
+type resgateChecker struct {
+	url    string
+	client *http.Client
+}
+
+// Check considers Resgate reachable as long as it answers without a server error
+func (r *resgateChecker) Check(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("invalid Resgate URL: %w", err)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Resgate unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("Resgate answered %s", resp.Status)
+	}
+	return nil
+}
+
+func (r *resgateChecker) Name() string {
+	return "resgate"
+}
+
+var _ Checker = (*resgateChecker)(nil)
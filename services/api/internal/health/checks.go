@@ -0,0 +1,114 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+)
+
+// CheckResult is the outcome of a single health check, along with its most recent status changes
+type CheckResult struct {
+	Status      string
+	Error       string `json:",omitempty"`
+	Optional    bool
+	Latency     time.Duration
+	CheckedAt   time.Time
+	ChangedAt   time.Time
+	SinceChange time.Duration
+	Transitions []Transition
+}
+
+// checkCache keeps the latest check results for the health check interval, so that bursts of health requests do not
+// hammer the checked dependencies. Callers arriving while the checks run wait for that run instead of starting another.
+type checkCache struct {
+	mu        sync.Mutex
+	results   map[string]CheckResult
+	updatedAt time.Time
+}
+
+// checkResults returns the cached check results, running the checks again when the cache is older than the interval
+// or when refresh is set
+func (hc *HealthChecker) checkResults(refresh bool) map[string]CheckResult {
+	hc.cache.mu.Lock()
+	defer hc.cache.mu.Unlock()
+
+	if refresh || hc.cache.results == nil || time.Since(hc.cache.updatedAt) >= hc.checkInterval() {
+		results := hc.runChecks()
+		for _, transition := range hc.recordTransitions(results) {
+			hc.publishTransition(transition)
+		}
+
+		hc.cache.results = results
+		hc.cache.updatedAt = time.Now()
+	}
+
+	results := maps.Clone(hc.cache.results)
+	for name, result := range results {
+		result.SinceChange = time.Since(result.ChangedAt)
+		results[name] = result
+	}
+
+	return results
+}
+
+// runChecks runs all checks concurrently, each with its own timeout
+func (hc *HealthChecker) runChecks() map[string]CheckResult {
+	results := make([]CheckResult, len(hc.checks))
+
+	var wg sync.WaitGroup
+	for i, check := range hc.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = hc.runCheck(check)
+		}()
+	}
+	wg.Wait()
+
+	byName := make(map[string]CheckResult, len(results))
+	for i, check := range hc.checks {
+		byName[check.Name()] = results[i]
+	}
+
+	return byName
+}
+
+// runCheck runs a single check, giving up once its timeout expires even if the checker ignores its context
+func (hc *HealthChecker) runCheck(check check) CheckResult {
+	timeout := check.timeout
+	if timeout == 0 {
+		timeout = hc.checkTimeout()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() { errCh <- check.Check(ctx) }()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+
+	result := CheckResult{
+		Status:    StatusHealthy,
+		Optional:  check.optional,
+		Latency:   time.Since(start),
+		CheckedAt: start,
+	}
+	if err != nil {
+		result.Status = StatusUnhealthy
+		if check.optional {
+			result.Status = StatusDegraded
+		}
+		result.Error = err.Error()
+	}
+
+	return result
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package health
+
+import "errors"
+
+// freeDiskSpace is not supported on this platform
+func freeDiskSpace(string) (uint64, error) {
+	return 0, errors.New("free disk space is not supported on this platform")
+}
@@ -0,0 +1,41 @@
+package health
+
+import (
+	"slices"
+	"sync"
+)
+
+// readinessGates are the dependencies the service waits for before it can be ready, such as a database still
+// unreachable at startup.
+type readinessGates struct {
+	mu      sync.Mutex
+	pending []string
+}
+
+// WaitFor keeps the service unready, whatever SetReady, until the returned function is called.
+func (hc *HealthChecker) WaitFor(name string) (done func()) {
+	hc.gates.mu.Lock()
+	defer hc.gates.mu.Unlock()
+
+	hc.gates.pending = append(hc.gates.pending, name)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			hc.gates.mu.Lock()
+			defer hc.gates.mu.Unlock()
+
+			if i := slices.Index(hc.gates.pending, name); i >= 0 {
+				hc.gates.pending = slices.Delete(hc.gates.pending, i, i+1)
+			}
+		})
+	}
+}
+
+// waitingFor returns the dependencies the service still waits for.
+func (hc *HealthChecker) waitingFor() []string {
+	hc.gates.mu.Lock()
+	defer hc.gates.mu.Unlock()
+
+	return slices.Clone(hc.gates.pending)
+}
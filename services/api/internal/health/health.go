@@ -2,22 +2,51 @@ package health
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
+	"os"
 	"runtime"
+	"sync/atomic"
 	"time"
 
+	"github.com/Autherain/go_cyber/internal/tracing"
 	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Overall statuses of the service. A service is degraded when only optional checks fail.
+const (
+	StatusHealthy   = "healthy"
+	StatusDegraded  = "degraded"
+	StatusUnhealthy = "unhealthy"
 )
 
 // HealthChecker manages the health check functionality
 type HealthChecker struct {
-	nc          *nats.Conn
-	config      config
-	startTime   time.Time
-	stopCh      chan struct{}
-	versionInfo *VersionInfo
-	checks      []Checker
+	nc            *nats.Conn
+	config        config
+	startTime     time.Time
+	stopCh        chan struct{}
+	versionInfo   *VersionInfo
+	checks        []check
+	ready         atomic.Bool
+	subscriptions []*nats.Subscription
+	cache         checkCache
+	history       map[string]*checkHistory
+	pools         map[string]*sql.DB
+	gates         readinessGates
+	interval      atomic.Int64 // Reloadable config.Interval
+	timeout       atomic.Int64 // Reloadable config.Timeout
+}
+
+// check is a registered checker. Failing optional checks degrade the service without making it unhealthy or unready.
+// A zero timeout stands for the health checker timeout.
+type check struct {
+	Checker
+	optional bool
+	timeout  time.Duration
 }
 
 // config contains configuration for the health checker
@@ -26,22 +55,30 @@ type config struct {
 	Timeout     time.Duration
 	Subject     string
 	StatusTopic string
+	EventsTopic string
 	ServiceName string
+	Instance    string
+	HistorySize int
 }
 
-// Status represents the overall status of the service
+// Status represents the overall status of the service. Ready is false until the service is marked ready, while it
+// waits for a dependency, once it is draining for shutdown, or when a critical check fails.
 type Status struct {
 	Status      string
+	Ready       bool
 	SystemInfo  map[string]string
 	Version     string
 	Environment string
 	Uptime      string
 	Timestamp   time.Time
-	Checks      map[string]string
+	Checks      map[string]CheckResult
+	Pools       map[string]PoolStats `json:",omitempty"`
+	WaitingFor  []string             `json:",omitempty"` // Dependencies keeping the service unready
 	ServiceName string
+	Instance    string
 }
 
-// New creates a new health checker with the provided options
+// New creates a new health checker with the provided options. It panics when two checks have the same name.
 func New(nc *nats.Conn, versionInfo *VersionInfo, opts ...Option) *HealthChecker {
 	if nc == nil {
 		panic("NATS connection is required")
@@ -57,12 +94,17 @@ func New(nc *nats.Conn, versionInfo *VersionInfo, opts ...Option) *HealthChecker
 			Timeout:     time.Second * 5,
 			Subject:     "health",
 			StatusTopic: "health.status",
+			EventsTopic: "health.events",
 			ServiceName: "service",
+			Instance:    defaultInstance(),
+			HistorySize: 10,
 		},
 		startTime:   time.Now(),
 		stopCh:      make(chan struct{}),
 		versionInfo: versionInfo,
-		checks:      make([]Checker, 0),
+		checks:      make([]check, 0),
+		history:     make(map[string]*checkHistory),
+		pools:       make(map[string]*sql.DB),
 	}
 
 	// Apply all options
@@ -70,20 +112,47 @@ func New(nc *nats.Conn, versionInfo *VersionInfo, opts ...Option) *HealthChecker
 		opt(hc)
 	}
 
+	// Results and transition histories are keyed by check name
+	names := make(map[string]bool, len(hc.checks))
+	for _, check := range hc.checks {
+		if names[check.Name()] {
+			panic(fmt.Sprintf("Duplicate health check %q", check.Name()))
+		}
+		names[check.Name()] = true
+	}
+	hc.SetInterval(hc.config.Interval)
+	hc.SetTimeout(hc.config.Timeout)
+
 	return hc
 }
 
 // Start begins the health check service
 func (hc *HealthChecker) Start() error {
-	// Subscribe to health check requests using service name
+	// Subscribe to health, liveness and readiness requests using service name
 	subject := fmt.Sprintf("%s.%s", hc.config.Subject, hc.config.ServiceName)
-	_, err := hc.nc.Subscribe(subject, func(msg *nats.Msg) {
-		health := hc.GetHealth()
-		response, _ := json.Marshal(health)
-		msg.Respond(response)
-	})
-	if err != nil {
-		return fmt.Errorf("failed to subscribe to health check: %v", err)
+	probes := map[string]func() Status{
+		subject:            hc.GetHealth,
+		subject + ".live":  hc.Liveness,
+		subject + ".ready": hc.Readiness,
+	}
+
+	for probeSubject, probe := range probes {
+		sub, err := hc.nc.Subscribe(probeSubject, func(msg *nats.Msg) {
+			_, span := tracing.Tracer().Start(
+				tracing.ExtractNATS(context.Background(), msg),
+				"health.request",
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(attribute.String("messaging.destination.name", msg.Subject)),
+			)
+			defer span.End()
+
+			response, _ := json.Marshal(probe())
+			msg.Respond(response)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to health check: %v", err)
+		}
+		hc.subscriptions = append(hc.subscriptions, sub)
 	}
 
 	// Start periodic health status publishing
@@ -91,15 +160,41 @@ func (hc *HealthChecker) Start() error {
 	return nil
 }
 
-// publishHealthStatus periodically publishes the health status
+// SetReady marks the service as ready or not to receive traffic, e.g. once startup is done or when draining for
+// shutdown.
+func (hc *HealthChecker) SetReady(ready bool) {
+	hc.ready.Store(ready)
+}
+
+// SetInterval changes the health check interval at runtime, the publication period changing from the next tick
+func (hc *HealthChecker) SetInterval(interval time.Duration) {
+	hc.interval.Store(int64(interval))
+}
+
+// SetTimeout changes at runtime the default timeout of each health check
+func (hc *HealthChecker) SetTimeout(timeout time.Duration) {
+	hc.timeout.Store(int64(timeout))
+}
+
+func (hc *HealthChecker) checkInterval() time.Duration { return time.Duration(hc.interval.Load()) }
+func (hc *HealthChecker) checkTimeout() time.Duration  { return time.Duration(hc.timeout.Load()) }
+
+// publishHealthStatus periodically publishes the health status, which also serves as a heartbeat of the service.
+// Check status changes are published separately on the events topic as they are detected.
 func (hc *HealthChecker) publishHealthStatus() {
-	ticker := time.NewTicker(hc.config.Interval)
+	interval := hc.checkInterval()
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			health := hc.GetHealth()
+			if current := hc.checkInterval(); current != interval {
+				interval = current
+				ticker.Reset(interval)
+			}
+
+			health := hc.getHealth(true)
 			healthData, _ := json.Marshal(health)
 			topic := fmt.Sprintf("%s.%s", hc.config.StatusTopic, hc.config.ServiceName)
 			hc.nc.Publish(topic, healthData)
@@ -109,33 +204,70 @@ func (hc *HealthChecker) publishHealthStatus() {
 	}
 }
 
-// GetHealth returns the current health status
+// Liveness returns the status of the process itself, without running any check: a live service only needs a restart
+// when it stops answering. Its Ready field only reflects SetReady and the dependencies waited for, the checks being
+// left to Readiness.
+func (hc *HealthChecker) Liveness() Status {
+	status := hc.newStatus()
+	status.WaitingFor = hc.waitingFor()
+	status.Ready = hc.ready.Load() && len(status.WaitingFor) == 0
+
+	return status
+}
+
+// Readiness returns the full health status. Readiness probes should only consider its Ready field.
+func (hc *HealthChecker) Readiness() Status {
+	return hc.GetHealth()
+}
+
+// GetHealth returns the current health status, from check results cached for the health check interval
 func (hc *HealthChecker) GetHealth() Status {
-	status := Status{
-		Status:      "healthy",
+	return hc.getHealth(false)
+}
+
+func (hc *HealthChecker) getHealth(refresh bool) Status {
+	status := hc.newStatus()
+	status.WaitingFor = hc.waitingFor()
+	status.Ready = hc.ready.Load() && len(status.WaitingFor) == 0
+	status.Checks = hc.checkResults(refresh)
+	status.Pools = hc.poolStats()
+
+	for _, result := range status.Checks {
+		switch result.Status {
+		case StatusDegraded:
+			if status.Status == StatusHealthy {
+				status.Status = StatusDegraded
+			}
+		case StatusUnhealthy:
+			status.Status = StatusUnhealthy
+			status.Ready = false
+		}
+	}
+
+	return status
+}
+
+func (hc *HealthChecker) newStatus() Status {
+	return Status{
+		Status:      StatusHealthy,
 		SystemInfo:  getSystemInfo(),
 		Version:     hc.versionInfo.GetVersionString(),
 		Environment: hc.versionInfo.Environment,
 		Uptime:      time.Since(hc.startTime).String(),
 		Timestamp:   time.Now(),
-		Checks:      make(map[string]string),
+		Checks:      make(map[string]CheckResult),
 		ServiceName: hc.config.ServiceName, // Include service name in status
+		Instance:    hc.config.Instance,
 	}
+}
 
-	// Perform all health checks
-	ctx, cancel := context.WithTimeout(context.Background(), hc.config.Timeout)
-	defer cancel()
-
-	for _, check := range hc.checks {
-		if err := check.Check(ctx); err != nil {
-			status.Status = "unhealthy"
-			status.Checks[check.Name()] = fmt.Sprintf("unhealthy: %v", err)
-		} else {
-			status.Checks[check.Name()] = "healthy"
-		}
+// defaultInstance identifies the replica by its host name, which is the container ID under docker
+func defaultInstance() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "unknown"
 	}
-
-	return status
+	return hostname
 }
 
 // getSystemInfo collects system-level information
@@ -150,5 +282,9 @@ func getSystemInfo() map[string]string {
 }
 
 func (hc *HealthChecker) Stop() {
+	hc.SetReady(false)
+	for _, sub := range hc.subscriptions {
+		sub.Unsubscribe()
+	}
 	close(hc.stopCh)
 }
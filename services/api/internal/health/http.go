@@ -0,0 +1,42 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Handler returns an HTTP handler exposing the liveness, readiness and health probes as JSON:
+//   - /livez answers 200 as long as the service is running;
+//   - /readyz answers 503 when the service is not ready;
+//   - /healthz answers 503 when the service is unhealthy, and 200 when healthy or degraded.
+func (hc *HealthChecker) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /livez", func(w http.ResponseWriter, r *http.Request) {
+		writeStatus(w, http.StatusOK, hc.Liveness())
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		status := hc.Readiness()
+		writeStatus(w, statusCode(status.Ready), status)
+	})
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		status := hc.GetHealth()
+		writeStatus(w, statusCode(status.Status != StatusUnhealthy), status)
+	})
+
+	return mux
+}
+
+func statusCode(ok bool) int {
+	if ok {
+		return http.StatusOK
+	}
+	return http.StatusServiceUnavailable
+}
+
+func writeStatus(w http.ResponseWriter, code int, status Status) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(status)
+}
@@ -18,22 +18,52 @@ type Checker interface {
 	Name() string
 }
 
-// WithNATSCheck adds NATS connection health check
-func WithNATSCheck(conn *nats.Conn) Option {
+// WithCheck adds a custom health check
+func WithCheck(checker Checker) Option {
 	return func(hc *HealthChecker) {
-		checker := &natsChecker{conn: conn}
-		hc.checks = append(hc.checks, checker)
+		hc.checks = append(hc.checks, check{Checker: checker})
 	}
 }
 
-// WithSQLCheck adds SQL database health check
-func WithSQLCheck(db *sql.DB) Option {
+// Optional marks the checks added by the given option as optional: when they fail, the service is reported as
+// degraded but stays ready
+func Optional(option Option) Option {
 	return func(hc *HealthChecker) {
-		checker := &sqlChecker{db: db}
-		hc.checks = append(hc.checks, checker)
+		added := len(hc.checks)
+		option(hc)
+		for i := added; i < len(hc.checks); i++ {
+			hc.checks[i].optional = true
+		}
 	}
 }
 
+// WithCheckTimeout overrides the health checker timeout for the checks added by the given option
+func WithCheckTimeout(timeout time.Duration, option Option) Option {
+	return func(hc *HealthChecker) {
+		added := len(hc.checks)
+		option(hc)
+		for i := added; i < len(hc.checks); i++ {
+			hc.checks[i].timeout = timeout
+		}
+	}
+}
+
+// WithNATSCheck adds NATS connection health check
+func WithNATSCheck(conn *nats.Conn) Option {
+	return WithCheck(&natsChecker{conn: conn})
+}
+
+// WithSQLCheck adds SQL database health check
+func WithSQLCheck(db *sql.DB) Option {
+	return WithCheck(&sqlChecker{db: db})
+}
+
+// WithMigrationCheck adds a check failing while the database migrations are missing or being applied, as tracked by
+// the given migrations table
+func WithMigrationCheck(db *sql.DB, table string) Option {
+	return WithCheck(&migrationChecker{db: db, table: table})
+}
+
 // WithInterval sets the health check interval
 func WithInterval(interval time.Duration) Option {
 	return func(hc *HealthChecker) {
@@ -41,7 +71,7 @@ func WithInterval(interval time.Duration) Option {
 	}
 }
 
-// WithTimeout sets the health check timeout
+// WithTimeout sets the default timeout of each health check
 func WithTimeout(timeout time.Duration) Option {
 	return func(hc *HealthChecker) {
 		hc.config.Timeout = timeout
@@ -55,6 +85,27 @@ func WithSubject(subject string) Option {
 	}
 }
 
+// WithStatusTopic sets the NATS topic prefix the health status is periodically published on
+func WithStatusTopic(topic string) Option {
+	return func(hc *HealthChecker) {
+		hc.config.StatusTopic = topic
+	}
+}
+
+// WithEventsTopic sets the NATS topic prefix check transitions are published on
+func WithEventsTopic(topic string) Option {
+	return func(hc *HealthChecker) {
+		hc.config.EventsTopic = topic
+	}
+}
+
+// WithHistorySize sets the number of recent transitions kept for each check
+func WithHistorySize(size int) Option {
+	return func(hc *HealthChecker) {
+		hc.config.HistorySize = size
+	}
+}
+
 // WithServiceName sets the service name
 func WithServiceName(name string) Option {
 	return func(hc *HealthChecker) {
@@ -62,6 +113,13 @@ func WithServiceName(name string) Option {
 	}
 }
 
+// WithInstance sets the identifier of the service replica, which defaults to the host name
+func WithInstance(instance string) Option {
+	return func(hc *HealthChecker) {
+		hc.config.Instance = instance
+	}
+}
+
 // Internal checker implementations
 type natsChecker struct {
 	conn *nats.Conn
@@ -93,3 +151,26 @@ func (s *sqlChecker) Name() string {
 }
 
 var _ Checker = (*sqlChecker)(nil)
+
+type migrationChecker struct {
+	db    *sql.DB
+	table string
+}
+
+func (m *migrationChecker) Check(ctx context.Context) error {
+	var dirty bool
+	query := fmt.Sprintf("SELECT dirty FROM %s LIMIT 1", m.table)
+	if err := m.db.QueryRowContext(ctx, query).Scan(&dirty); err != nil {
+		return fmt.Errorf("migrations not applied: %w", err)
+	}
+	if dirty {
+		return fmt.Errorf("migrations in progress")
+	}
+	return nil
+}
+
+func (m *migrationChecker) Name() string {
+	return "migrations"
+}
+
+var _ Checker = (*migrationChecker)(nil)
@@ -0,0 +1,50 @@
+package health
+
+import (
+	"database/sql"
+	"time"
+)
+
+// PoolStats reports the state of a database connection pool.
+type PoolStats struct {
+	MaxOpenConnections int
+	OpenConnections    int
+	InUse              int
+	Idle               int
+	WaitCount          int64
+	WaitDuration       time.Duration
+	MaxIdleClosed      int64
+	MaxIdleTimeClosed  int64
+	MaxLifetimeClosed  int64
+}
+
+// WithSQLPoolStats reports the connection pool stats of the DB in the health status, under the given name
+func WithSQLPoolStats(name string, db *sql.DB) Option {
+	return func(hc *HealthChecker) {
+		hc.pools[name] = db
+	}
+}
+
+func (hc *HealthChecker) poolStats() map[string]PoolStats {
+	if len(hc.pools) == 0 {
+		return nil
+	}
+
+	pools := make(map[string]PoolStats, len(hc.pools))
+	for name, db := range hc.pools {
+		stats := db.Stats()
+		pools[name] = PoolStats{
+			MaxOpenConnections: stats.MaxOpenConnections,
+			OpenConnections:    stats.OpenConnections,
+			InUse:              stats.InUse,
+			Idle:               stats.Idle,
+			WaitCount:          stats.WaitCount,
+			WaitDuration:       stats.WaitDuration,
+			MaxIdleClosed:      stats.MaxIdleClosed,
+			MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+			MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+		}
+	}
+
+	return pools
+}
@@ -0,0 +1,113 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Autherain/go_cyber/internal/tracing"
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Transition is a change of status of a health check
+type Transition struct {
+	Check string
+	From  string
+	To    string
+	Error string `json:",omitempty"`
+	At    time.Time
+}
+
+// checkHistory tracks the status changes of a single check
+type checkHistory struct {
+	status      string
+	changedAt   time.Time
+	transitions *ring[Transition]
+}
+
+// recordTransitions compares fresh check results to the previous ones, filling in their change time and recent
+// transitions, and returns the transitions that just happened. The first result of a check is not a transition.
+func (hc *HealthChecker) recordTransitions(results map[string]CheckResult) []Transition {
+	var transitions []Transition
+
+	for name, result := range results {
+		history, ok := hc.history[name]
+		if !ok {
+			history = &checkHistory{
+				status:      result.Status,
+				changedAt:   result.CheckedAt,
+				transitions: newRing[Transition](hc.config.HistorySize),
+			}
+			hc.history[name] = history
+		}
+
+		if history.status != result.Status {
+			transition := Transition{
+				Check: name,
+				From:  history.status,
+				To:    result.Status,
+				Error: result.Error,
+				At:    result.CheckedAt,
+			}
+			history.status = result.Status
+			history.changedAt = result.CheckedAt
+			history.transitions.push(transition)
+			transitions = append(transitions, transition)
+		}
+
+		result.ChangedAt = history.changedAt
+		result.Transitions = history.transitions.items()
+		results[name] = result
+	}
+
+	return transitions
+}
+
+// publishTransition publishes a check transition on the events topic of the service, within its own trace
+func (hc *HealthChecker) publishTransition(transition Transition) {
+	msg := nats.NewMsg(fmt.Sprintf("%s.%s", hc.config.EventsTopic, hc.config.ServiceName))
+	msg.Data, _ = json.Marshal(transition)
+
+	ctx, span := tracing.Tracer().Start(context.Background(), "health.transition",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.destination.name", msg.Subject),
+			attribute.String("health.check", transition.Check),
+			attribute.String("health.status", transition.To),
+		),
+	)
+	defer span.End()
+
+	tracing.InjectNATS(ctx, msg)
+	hc.nc.PublishMsg(msg)
+}
+
+// ring is a fixed-size buffer keeping the most recent values pushed to it
+type ring[T any] struct {
+	values []T
+	next   int
+	full   bool
+}
+
+func newRing[T any](size int) *ring[T] {
+	return &ring[T]{values: make([]T, max(size, 1))}
+}
+
+func (r *ring[T]) push(value T) {
+	r.values[r.next] = value
+	r.next = (r.next + 1) % len(r.values)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// items returns a copy of the values, oldest first
+func (r *ring[T]) items() []T {
+	if !r.full {
+		return append([]T(nil), r.values[:r.next]...)
+	}
+	return append(append([]T(nil), r.values[r.next:]...), r.values[:r.next]...)
+}
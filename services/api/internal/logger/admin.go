@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// LevelRequest changes the log level at runtime. An empty component targets the global level, and an empty level
+// reverts the target to the configured level.
+type LevelRequest struct {
+	Level     string `json:"level,omitempty"`
+	Component string `json:"component,omitempty"`
+	TTL       string `json:"ttl,omitempty"` // Go duration, the default TTL applies when empty and "0s" never reverts
+}
+
+// LevelResponse is the reply to a LevelRequest.
+type LevelResponse struct {
+	Level     LogLevel   `json:"level,omitempty"`
+	Component string     `json:"component,omitempty"`
+	RevertAt  *time.Time `json:"revertAt,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// ServeLevelRequests answers the LevelRequest messages received on the subject, reverting the changes after the
+// default TTL unless the request carries its own.
+func (l *Logger) ServeLevelRequests(nc *nats.Conn, subject string, defaultTTL time.Duration) (*nats.Subscription, error) {
+	sub, err := nc.Subscribe(subject, func(msg *nats.Msg) {
+		response := l.applyLevelRequest(msg.Data, defaultTTL)
+		data, _ := json.Marshal(response)
+		if err := msg.Respond(data); err != nil && msg.Reply != "" {
+			l.Error("Could not reply to log level request", "error", err)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not subscribe to %s: %w", subject, err)
+	}
+
+	return sub, nil
+}
+
+func (l *Logger) applyLevelRequest(data []byte, defaultTTL time.Duration) LevelResponse {
+	var request LevelRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		return LevelResponse{Error: fmt.Sprintf("invalid request: %v", err)}
+	}
+
+	if request.Level == "" {
+		l.ResetLevel(request.Component)
+		l.Info("Log level reset", "component", request.Component, "level", l.Level(request.Component))
+		return LevelResponse{Level: l.Level(request.Component), Component: request.Component}
+	}
+
+	level := LogLevel(request.Level)
+	if !level.valid() {
+		return LevelResponse{Component: request.Component, Error: fmt.Sprintf("unknown level %q", request.Level)}
+	}
+
+	ttl := defaultTTL
+	if request.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(request.TTL); err != nil || ttl < 0 {
+			return LevelResponse{Component: request.Component, Error: fmt.Sprintf("invalid TTL %q", request.TTL)}
+		}
+	}
+
+	l.SetLevel(request.Component, level, ttl)
+
+	response := LevelResponse{Level: level, Component: request.Component}
+	if ttl > 0 {
+		revertAt := time.Now().Add(ttl)
+		response.RevertAt = &revertAt
+	}
+	l.Info("Log level changed", "component", request.Component, "level", level, "ttl", ttl)
+
+	return response
+}
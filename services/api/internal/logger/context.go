@@ -0,0 +1,35 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+type contextKey struct{}
+
+// With returns a child logger that includes the given attributes in each record.
+func (l *Logger) With(args ...any) *Logger {
+	return &Logger{slog: l.slog.With(args...), levels: l.levels, outputs: l.outputs, component: l.component}
+}
+
+// NewContext returns a copy of the context carrying the logger.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger carried by the context, or one writing to the default slog logger.
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return l
+	}
+
+	return fromSlog(slog.Default())
+}
+
+func fromSlog(l *slog.Logger) *Logger {
+	if h, ok := l.Handler().(*levelHandler); ok {
+		return &Logger{slog: l, levels: h.levels, component: h.component}
+	}
+
+	return &Logger{slog: l, levels: newLevels(slog.LevelInfo)}
+}
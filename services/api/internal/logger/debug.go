@@ -0,0 +1,48 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// debugHandler adds to each record the function of the log call and the ID of the logging goroutine, to debug worker
+// contention.
+type debugHandler struct {
+	slog.Handler
+	caller      bool
+	goroutineID bool
+}
+
+func (h *debugHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.caller && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		r.AddAttrs(slog.String("func", frame.Function))
+	}
+	if h.goroutineID {
+		r.AddAttrs(slog.Uint64("goroutine", goroutineID()))
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *debugHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &debugHandler{Handler: h.Handler.WithAttrs(attrs), caller: h.caller, goroutineID: h.goroutineID}
+}
+
+func (h *debugHandler) WithGroup(name string) slog.Handler {
+	return &debugHandler{Handler: h.Handler.WithGroup(name), caller: h.caller, goroutineID: h.goroutineID}
+}
+
+// goroutineID parses the ID of the current goroutine from the header of its stack trace, "goroutine N [status]:".
+// Handlers run on the goroutine of the log call.
+func goroutineID() uint64 {
+	var buf [64]byte
+	stack := strings.TrimPrefix(string(buf[:runtime.Stack(buf[:], false)]), "goroutine ")
+	field, _, _ := strings.Cut(stack, " ")
+	id, _ := strconv.ParseUint(field, 10, 64)
+
+	return id
+}
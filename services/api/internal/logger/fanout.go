@@ -0,0 +1,68 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/nats-io/nats.go"
+)
+
+// fanoutHandler passes each record to every handler enabled for its level.
+type fanoutHandler []slog.Handler
+
+func (h fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (h fanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, handler := range h {
+		if handler.Enabled(ctx, r.Level) {
+			errs = append(errs, handler.Handle(ctx, r.Clone()))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (h fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	children := make(fanoutHandler, 0, len(h))
+	for _, handler := range h {
+		children = append(children, handler.WithAttrs(attrs))
+	}
+
+	return children
+}
+
+func (h fanoutHandler) WithGroup(name string) slog.Handler {
+	children := make(fanoutHandler, 0, len(h))
+	for _, handler := range h {
+		children = append(children, handler.WithGroup(name))
+	}
+
+	return children
+}
+
+// natsPublisher publishes each written record on a subject. Records are dropped until a connection is set, since the
+// logger is created before connecting to NATS.
+type natsPublisher struct {
+	subject string
+	conn    atomic.Pointer[nats.Conn]
+}
+
+func (p *natsPublisher) Write(record []byte) (int, error) {
+	if conn := p.conn.Load(); conn != nil {
+		// Publishing errors are not logged, which would loop back here
+		_ = conn.Publish(p.subject, record)
+	}
+
+	return len(record), nil
+}
@@ -0,0 +1,140 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const backupTimeLayout = "20060102T150405.000"
+
+// FileConfig configures the log file output. The file is rotated once it exceeds MaxSize or is older than
+// RotateInterval; zero disables the corresponding rotation or retention rule.
+type FileConfig struct {
+	Path           string
+	MaxSize        int64 // In bytes
+	RotateInterval time.Duration
+	MaxBackups     int           // Number of rotated files kept
+	MaxAge         time.Duration // Age after which rotated files are removed
+}
+
+// rotatingFile writes to the configured path, renaming the file with its rotation time as suffix when rotating.
+type rotatingFile struct {
+	FileConfig
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+func openRotatingFile(cfg FileConfig) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("could not create log directory: %w", err)
+	}
+
+	f := &rotatingFile{FileConfig: cfg}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// A failed rotation keeps writing to the current file, and is tried again on the next write
+	var rotateErr error
+	if f.shouldRotate(len(p)) {
+		rotateErr = f.rotate()
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, errors.Join(rotateErr, err)
+}
+
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.file.Close()
+}
+
+func (f *rotatingFile) shouldRotate(size int) bool {
+	if f.size == 0 {
+		return false
+	}
+
+	return (f.MaxSize > 0 && f.size+int64(size) > f.MaxSize) ||
+		(f.RotateInterval > 0 && time.Since(f.openedAt) >= f.RotateInterval)
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("could not open log file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("could not stat log file: %w", err)
+	}
+
+	// An existing file keeps its age across restarts, as far as its last write tells
+	openedAt := time.Now()
+	if info.Size() > 0 {
+		openedAt = info.ModTime()
+	}
+
+	f.file, f.size, f.openedAt = file, info.Size(), openedAt
+	return nil
+}
+
+// rotate renames the current file before opening a new one, so that the current file is kept open for writing if
+// either step fails.
+func (f *rotatingFile) rotate() error {
+	if err := os.Rename(f.Path, f.Path+"."+time.Now().Format(backupTimeLayout)); err != nil {
+		return fmt.Errorf("could not rotate log file: %w", err)
+	}
+
+	rotated := f.file
+	if err := f.open(); err != nil {
+		return err
+	}
+	rotated.Close()
+
+	f.removeExpiredBackups()
+	return nil
+}
+
+// removeExpiredBackups applies the retention rules, on a best effort basis.
+func (f *rotatingFile) removeExpiredBackups() {
+	backups, err := filepath.Glob(f.Path + ".*")
+	if err != nil {
+		return
+	}
+
+	// The suffix layout sorts chronologically
+	slices.SortFunc(backups, func(a, b string) int { return strings.Compare(b, a) })
+
+	for i, backup := range backups {
+		expired := f.MaxBackups > 0 && i >= f.MaxBackups
+		if !expired && f.MaxAge > 0 {
+			info, err := os.Stat(backup)
+			expired = err == nil && time.Since(info.ModTime()) > f.MaxAge
+		}
+
+		if expired {
+			os.Remove(backup)
+		}
+	}
+}
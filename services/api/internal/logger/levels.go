@@ -0,0 +1,157 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// levels holds the minimum levels of a logger and its components. The global level and the per component overrides
+// can be changed at runtime, and revert to the configured level once their TTL expires.
+type levels struct {
+	baseline slog.Level
+	global   slog.LevelVar
+
+	mu        sync.RWMutex
+	overrides map[string]slog.Level
+	reverts   map[string]*time.Timer
+}
+
+func newLevels(baseline slog.Level) *levels {
+	l := &levels{
+		baseline:  baseline,
+		overrides: make(map[string]slog.Level),
+		reverts:   make(map[string]*time.Timer),
+	}
+	l.global.Set(baseline)
+
+	return l
+}
+
+// level returns the minimum level of the component, or the global level when the component has no override.
+func (l *levels) level(component string) slog.Level {
+	if component != "" {
+		l.mu.RLock()
+		level, ok := l.overrides[component]
+		l.mu.RUnlock()
+		if ok {
+			return level
+		}
+	}
+
+	return l.global.Level()
+}
+
+// set changes the level of the component, or the global level for an empty component. A positive TTL reverts the
+// change once expired, replacing any pending revert.
+func (l *levels) set(component string, level slog.Level, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if component == "" {
+		l.global.Set(level)
+	} else {
+		l.overrides[component] = level
+	}
+
+	l.stopRevert(component)
+	if ttl <= 0 {
+		return
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(ttl, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		// A later change replaced this revert
+		if l.reverts[component] != timer {
+			return
+		}
+		l.resetLocked(component)
+	})
+	l.reverts[component] = timer
+}
+
+// setBaseline changes the configured level, which also becomes the global level unless a runtime change is pending.
+func (l *levels) setBaseline(level slog.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.baseline = level
+	if _, pending := l.reverts[""]; !pending {
+		l.global.Set(level)
+	}
+}
+
+// reset reverts the component, or the global level for an empty component, to the configured level.
+func (l *levels) reset(component string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.resetLocked(component)
+}
+
+func (l *levels) resetLocked(component string) {
+	l.stopRevert(component)
+
+	if component == "" {
+		l.global.Set(l.baseline)
+	} else {
+		delete(l.overrides, component)
+	}
+}
+
+func (l *levels) stopRevert(component string) {
+	if timer, ok := l.reverts[component]; ok {
+		timer.Stop()
+		delete(l.reverts, component)
+	}
+}
+
+// levelHandler filters records with the runtime level of its component, the wrapped handler accepting every level.
+type levelHandler struct {
+	slog.Handler
+	levels    *levels
+	component string
+}
+
+func (h *levelHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.levels.level(h.component)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithAttrs(attrs), levels: h.levels, component: h.component}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithGroup(name), levels: h.levels, component: h.component}
+}
+
+// SetLevel changes at runtime the level of the component, or the global level for an empty component. A positive TTL
+// reverts the change to the configured level once expired.
+func (l *Logger) SetLevel(component string, level LogLevel, ttl time.Duration) {
+	l.levels.set(component, convertLevel(level), ttl)
+}
+
+// ResetLevel reverts the component, or the global level for an empty component, to the configured level.
+func (l *Logger) ResetLevel(component string) {
+	l.levels.reset(component)
+}
+
+// Level returns the current level of the component, or the global level for an empty component.
+func (l *Logger) Level(component string) LogLevel {
+	switch level := l.levels.level(component); {
+	case level <= LevelTrace:
+		return TraceLevel
+	case level <= slog.LevelDebug:
+		return DebugLevel
+	case level <= slog.LevelInfo:
+		return InfoLevel
+	case level <= slog.LevelWarn:
+		return WarnLevel
+	default:
+		return ErrorLevel
+	}
+}
@@ -1,17 +1,31 @@
 package logger
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"runtime"
+	"sync/atomic"
 	"time"
+
+	"github.com/nats-io/nats.go"
 )
 
 type (
-	Format   string
-	LogLevel string
+	Format     string
+	LogLevel   string
+	Output     string
+	TimeFormat string
 )
 
+// LevelTrace is below slog.LevelDebug, so that the very chatty traces (such as the RES protocol ones) can be enabled
+// separately from debug logs.
+const LevelTrace = slog.Level(-8)
+
+const traceLevelName = "TRACE"
+
 const (
 	JSONFormat Format = "json"
 	TextFormat Format = "text"
@@ -21,6 +35,14 @@ const (
 	InfoLevel  LogLevel = "info"
 	WarnLevel  LogLevel = "warn"
 	ErrorLevel LogLevel = "error"
+
+	StdoutOutput Output = "stdout"
+	StderrOutput Output = "stderr"
+	FileOutput   Output = "file"
+
+	RFC3339TimeFormat     TimeFormat = "rfc3339"
+	RFC3339NanoTimeFormat TimeFormat = "rfc3339nano"
+	UnixMilliTimeFormat   TimeFormat = "unixmilli"
 )
 
 func (f *Format) UnmarshalText(text []byte) error {
@@ -29,38 +51,163 @@ func (f *Format) UnmarshalText(text []byte) error {
 		*f = Format(text)
 		return nil
 	default:
-		*f = TextFormat
+		return fmt.Errorf("unknown log format %q", text)
+	}
+}
+
+func (o *Output) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case string(StdoutOutput), string(StderrOutput), string(FileOutput):
+		*o = Output(text)
 		return nil
+	default:
+		return fmt.Errorf("unknown log output %q", text)
 	}
 }
 
-func (l *LogLevel) UnmarshalText(text []byte) error {
+func (t *TimeFormat) UnmarshalText(text []byte) error {
 	switch string(text) {
-	case string(TraceLevel), string(DebugLevel), string(InfoLevel), string(WarnLevel), string(ErrorLevel):
-		*l = LogLevel(text)
+	case string(RFC3339TimeFormat), string(RFC3339NanoTimeFormat), string(UnixMilliTimeFormat):
+		*t = TimeFormat(text)
 		return nil
 	default:
-		*l = InfoLevel
+		return fmt.Errorf("unknown log time format %q", text)
+	}
+}
+
+func (l LogLevel) valid() bool {
+	switch l {
+	case TraceLevel, DebugLevel, InfoLevel, WarnLevel, ErrorLevel:
+		return true
+	default:
+		return false
+	}
+}
+
+func (l *LogLevel) UnmarshalText(text []byte) error {
+	switch level := LogLevel(text); {
+	case level.valid():
+		*l = level
 		return nil
+	default:
+		return fmt.Errorf("unknown log level %q", text)
 	}
 }
 
 type Config struct {
-	Format    Format
-	Level     LogLevel
-	AddSource bool
+	Format     Format
+	Level      LogLevel
+	AddSource  bool
+	TimeFormat TimeFormat
+	UTC        bool
+	// Debugging attributes, added to each record
+	AddCaller      bool // Function of the log call
+	AddGoroutineID bool
+	RedactKeys     []string        // Redacted in addition to DefaultRedactKeys
+	Sampling       *SamplingConfig // Nil logs every record
+
+	Output        Output
+	File          FileConfig // Used by the file output
+	ErrorsSubject string     // NATS subject errors are also published to, once connected with ConnectNATS
 }
 
 // Logger is the main logger struct that handles both standard and RES logging
 type Logger struct {
-	slog *slog.Logger
+	slog      *slog.Logger
+	levels    *levels
+	outputs   *outputs
+	component string
+}
+
+// outputs are the destinations of a logger that outlive its handlers.
+type outputs struct {
+	file   *rotatingFile
+	errors *natsPublisher
+	format atomic.Pointer[Format]
 }
 
-// NewLogger creates a new configured logger that can be used for both standard and RES logging
+// NewLogger creates a new configured logger that can be used for both standard and RES logging. A log file that can't
+// be opened is replaced by stderr.
 func NewLogger(cfg Config) *Logger {
-	level := convertLevel(cfg.Level)
-	handler := createHandler(cfg, level)
-	return &Logger{slog: slog.New(handler)}
+	outputs := &outputs{}
+
+	var (
+		writer  io.Writer = os.Stdout
+		fileErr error
+	)
+	switch cfg.Output {
+	case StderrOutput:
+		writer = os.Stderr
+	case FileOutput:
+		if outputs.file, fileErr = openRotatingFile(cfg.File); fileErr == nil {
+			writer = outputs.file
+		} else {
+			writer = os.Stderr
+		}
+	}
+
+	var errorsWriter io.Writer
+	if cfg.ErrorsSubject != "" {
+		outputs.errors = &natsPublisher{subject: cfg.ErrorsSubject}
+		errorsWriter = outputs.errors
+	}
+
+	logger := newLogger(cfg, writer, errorsWriter, outputs)
+	if fileErr != nil {
+		logger.Error("Could not open the log file, logging to stderr", "path", cfg.File.Path, "error", fileErr)
+	}
+
+	return logger
+}
+
+// newLogger returns a logger writing to w, and writing the errors as JSON to errorsWriter as well unless it is nil.
+func newLogger(cfg Config, w, errorsWriter io.Writer, outputs *outputs) *Logger {
+	levels := newLevels(convertLevel(cfg.Level))
+
+	outputs.format.Store(&cfg.Format)
+	handler := createHandler(cfg, w, LevelTrace, &outputs.format)
+	if errorsWriter != nil {
+		handler = fanoutHandler{handler, slog.NewJSONHandler(errorsWriter, handlerOptions(cfg, slog.LevelError))}
+	}
+	if cfg.AddCaller || cfg.AddGoroutineID {
+		handler = &debugHandler{Handler: handler, caller: cfg.AddCaller, goroutineID: cfg.AddGoroutineID}
+	}
+	if cfg.Sampling != nil {
+		handler = newSamplingHandler(handler, *cfg.Sampling)
+	}
+
+	return &Logger{slog: slog.New(&levelHandler{Handler: handler, levels: levels}), levels: levels, outputs: outputs}
+}
+
+// ConnectNATS starts publishing errors on the configured errors subject, if any.
+func (l *Logger) ConnectNATS(nc *nats.Conn) {
+	if l.outputs != nil && l.outputs.errors != nil {
+		l.outputs.errors.conn.Store(nc)
+	}
+}
+
+// Close closes the log file, if any.
+func (l *Logger) Close() error {
+	if l.outputs == nil || l.outputs.file == nil {
+		return nil
+	}
+
+	return l.outputs.file.Close()
+}
+
+// Component returns a child logger for the named component, whose level can be changed independently at runtime.
+func (l *Logger) Component(name string) *Logger {
+	handler := l.slog.Handler()
+	if h, ok := handler.(*levelHandler); ok {
+		handler = h.Handler
+	}
+
+	return &Logger{
+		slog:      slog.New(&levelHandler{Handler: handler, levels: l.levels, component: name}).With("component", name),
+		levels:    l.levels,
+		outputs:   l.outputs,
+		component: name,
+	}
 }
 
 func NewDefault() *Logger {
@@ -74,7 +221,9 @@ func NewDefault() *Logger {
 
 func convertLevel(level LogLevel) slog.Level {
 	switch level {
-	case TraceLevel, DebugLevel:
+	case TraceLevel:
+		return LevelTrace
+	case DebugLevel:
 		return slog.LevelDebug
 	case WarnLevel:
 		return slog.LevelWarn
@@ -85,40 +234,100 @@ func convertLevel(level LogLevel) slog.Level {
 	}
 }
 
-func createHandler(cfg Config, level slog.Level) slog.Handler {
-	opts := &slog.HandlerOptions{
+// createHandler returns a handler formatting the records with the current format.
+func createHandler(cfg Config, w io.Writer, level slog.Level, format *atomic.Pointer[Format]) slog.Handler {
+	opts := handlerOptions(cfg, level)
+	return &formatHandler{
+		json:   slog.NewJSONHandler(w, opts),
+		text:   slog.NewTextHandler(w, opts),
+		format: format,
+	}
+}
+
+func handlerOptions(cfg Config, level slog.Level) *slog.HandlerOptions {
+	redactor := newRedactor(cfg.RedactKeys)
+	return &slog.HandlerOptions{
 		Level:     level,
 		AddSource: cfg.AddSource,
 		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
-			if a.Key == slog.TimeKey {
-				return slog.Attr{
-					Key:   slog.TimeKey,
-					Value: slog.StringValue(time.Now().Format(time.RFC3339)),
+			if a.Key == slog.TimeKey && len(groups) == 0 && a.Value.Kind() == slog.KindTime {
+				return formatTime(cfg, a.Value.Time())
+			}
+			if a.Key == slog.LevelKey && len(groups) == 0 {
+				if level, ok := a.Value.Any().(slog.Level); ok && level <= LevelTrace {
+					return slog.String(slog.LevelKey, traceLevelName)
 				}
 			}
-			return a
+			return redactor.redact(a)
 		},
 	}
+}
 
-	if cfg.Format == JSONFormat {
-		return slog.NewJSONHandler(os.Stdout, opts)
+// formatTime renders the record time with the configured layout.
+func formatTime(cfg Config, t time.Time) slog.Attr {
+	if cfg.UTC {
+		t = t.UTC()
+	}
+
+	switch cfg.TimeFormat {
+	case UnixMilliTimeFormat:
+		return slog.Int64(slog.TimeKey, t.UnixMilli())
+	case RFC3339TimeFormat:
+		return slog.String(slog.TimeKey, t.Format(time.RFC3339))
+	default:
+		return slog.String(slog.TimeKey, t.Format(time.RFC3339Nano))
 	}
-	return slog.NewTextHandler(os.Stdout, opts)
 }
 
 // Standard logging methods
-func (l *Logger) Trace(msg string, args ...any) { l.slog.Debug(msg, args...) }
-func (l *Logger) Debug(msg string, args ...any) { l.slog.Debug(msg, args...) }
-func (l *Logger) Info(msg string, args ...any)  { l.slog.Info(msg, args...) }
-func (l *Logger) Warn(msg string, args ...any)  { l.slog.Warn(msg, args...) }
-func (l *Logger) Error(msg string, args ...any) { l.slog.Error(msg, args...) }
+func (l *Logger) Trace(msg string, args ...any) { l.log(LevelTrace, msg, args...) }
+func (l *Logger) Debug(msg string, args ...any) { l.log(slog.LevelDebug, msg, args...) }
+func (l *Logger) Info(msg string, args ...any)  { l.log(slog.LevelInfo, msg, args...) }
+func (l *Logger) Warn(msg string, args ...any)  { l.log(slog.LevelWarn, msg, args...) }
+func (l *Logger) Error(msg string, args ...any) { l.log(slog.LevelError, msg, args...) }
 
 // RES-compatible logging methods
-func (l *Logger) Tracef(format string, v ...interface{}) { l.Trace(fmt.Sprintf(format, v...)) }
-func (l *Logger) Debugf(format string, v ...interface{}) { l.Debug(fmt.Sprintf(format, v...)) }
-func (l *Logger) Infof(format string, v ...interface{})  { l.Info(fmt.Sprintf(format, v...)) }
-func (l *Logger) Warnf(format string, v ...interface{})  { l.Warn(fmt.Sprintf(format, v...)) }
-func (l *Logger) Errorf(format string, v ...interface{}) { l.Error(fmt.Sprintf(format, v...)) }
+
+// Tracef logs the RES protocol traces. The message payloads they hold, with the access tokens, the client metadata and
+// the ciphertexts, are only logged as their size.
+func (l *Logger) Tracef(format string, v ...interface{}) {
+	args := make([]any, len(v))
+	for i, arg := range v {
+		if payload, ok := arg.([]byte); ok {
+			arg = byteCount(payload)
+		}
+		args[i] = arg
+	}
+	l.log(LevelTrace, fmt.Sprintf(format, args...))
+}
+
+func (l *Logger) Debugf(format string, v ...interface{}) {
+	l.log(slog.LevelDebug, fmt.Sprintf(format, v...))
+}
+func (l *Logger) Infof(format string, v ...interface{}) {
+	l.log(slog.LevelInfo, fmt.Sprintf(format, v...))
+}
+func (l *Logger) Warnf(format string, v ...interface{}) {
+	l.log(slog.LevelWarn, fmt.Sprintf(format, v...))
+}
+func (l *Logger) Errorf(format string, v ...interface{}) {
+	l.log(slog.LevelError, fmt.Sprintf(format, v...))
+}
+
+// log records the call site of the logging method caller rather than the one of the method, for the source and caller
+// attributes.
+func (l *Logger) log(level slog.Level, msg string, args ...any) {
+	ctx := context.Background()
+	if !l.slog.Enabled(ctx, level) {
+		return
+	}
+
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:]) // Skips runtime.Callers, log and the logging method
+	record := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	record.Add(args...)
+	_ = l.slog.Handler().Handle(ctx, record)
+}
 
 // SlogLogger returns the underlying slog.Logger if needed
 func (l *Logger) SlogLogger() *slog.Logger {
@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+const redactedValue = "[REDACTED]"
+
+// DefaultRedactKeys are the attribute keys whose values never reach the logs: secrets, message ciphertexts and the
+// connection metadata identifying clients. Keys match regardless of case, dashes and underscores.
+var DefaultRedactKeys = []string{
+	"password", "secret", "token", "authorization", "cookie", "creds", "seed", "dsn", "connStr", "encryptedContent",
+	"nonce", "ip", "clientIP", "remoteAddr", "forwardedFor", "xForwardedFor", "xRealIP",
+}
+
+// redactor replaces the values of the redacted keys, and byte slices whatever their key, which may hold ciphertexts.
+type redactor struct {
+	keys map[string]struct{}
+}
+
+func newRedactor(keys []string) *redactor {
+	r := &redactor{keys: make(map[string]struct{}, len(DefaultRedactKeys)+len(keys))}
+	for _, key := range slices.Concat(DefaultRedactKeys, keys) {
+		r.keys[normalizeKey(key)] = struct{}{}
+	}
+
+	return r
+}
+
+func (r *redactor) redact(a slog.Attr) slog.Attr {
+	if a.Value.Kind() == slog.KindGroup {
+		return a
+	}
+
+	if _, ok := r.keys[normalizeKey(a.Key)]; ok {
+		return slog.String(a.Key, redactedValue)
+	}
+
+	if value, ok := a.Value.Any().([]byte); ok && a.Value.Kind() == slog.KindAny {
+		return slog.String(a.Key, byteCount(value))
+	}
+
+	return a
+}
+
+// byteCount stands for a byte slice in the logs.
+func byteCount(b []byte) string {
+	return fmt.Sprintf("[%d bytes]", len(b))
+}
+
+func normalizeKey(key string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+}
@@ -0,0 +1,131 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+var (
+	ciphertext = []byte("ciphertext-sentinel")
+	nonce      = []byte("nonce-sentin")
+)
+
+// sealedMessage has the shape of a room message, whose ciphertext is nested in a group.
+type sealedMessage struct {
+	id                      string
+	encryptedContent, nonce []byte
+}
+
+func (m sealedMessage) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("id", m.id),
+		slog.Any("encryptedContent", m.encryptedContent),
+		slog.Any("nonce", m.nonce),
+	)
+}
+
+func TestRedactor(t *testing.T) {
+	redactor := newRedactor([]string{"apiKey"})
+
+	tests := []struct {
+		attr slog.Attr
+		want string
+	}{
+		{slog.String("password", "hunter2"), redactedValue},
+		{slog.String("Authorization", "Bearer hunter2"), redactedValue},
+		{slog.String("x-forwarded-for", "203.0.113.7"), redactedValue},
+		{slog.String("remote_addr", "203.0.113.7:4222"), redactedValue},
+		{slog.String("API_KEY", "hunter2"), redactedValue},
+		{slog.Any("blob", ciphertext), fmt.Sprintf("[%d bytes]", len(ciphertext))},
+		{slog.String("room", "lobby"), "lobby"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.attr.Key, func(t *testing.T) {
+			if got := redactor.redact(tt.attr).Value.String(); got != tt.want {
+				t.Errorf("redact(%v) = %q, want %q", tt.attr, got, tt.want)
+			}
+		})
+	}
+
+	// Groups are left to the handler, which passes each of their attributes
+	group := slog.Group("db", slog.String("password", "hunter2"))
+	if got := redactor.redact(group); !got.Equal(group) {
+		t.Errorf("redact(%v) = %v, want it unchanged", group, got)
+	}
+}
+
+func TestRedactionFanout(t *testing.T) {
+	message := sealedMessage{id: "0194c3a2", encryptedContent: ciphertext, nonce: nonce}
+
+	for _, format := range []Format{JSONFormat, TextFormat} {
+		t.Run(string(format), func(t *testing.T) {
+			var output, errors bytes.Buffer
+			log := newLogger(Config{Format: format, Level: InfoLevel}, &output, &errors, &outputs{})
+
+			log.Info("Message received", "message", message)
+			log.With("message", message).Error("Message stored")
+
+			grouped := log.SlogLogger().WithGroup("request").With("authorization", "Bearer hunter2")
+			grouped.Error("Nested", slog.Group("db", slog.String("password", "hunter2")))
+			grouped.WithGroup("payload").Error("Nested message", "message", message, "blob", ciphertext)
+
+			assertRedacted(t, "output", output.String(), 4)
+			assertRedacted(t, "errors", errors.String(), 3)
+		})
+	}
+}
+
+// assertRedacted checks that the output holds the expected number of records, without any secret or message blob.
+func assertRedacted(t *testing.T, name, output string, records int) {
+	t.Helper()
+
+	if lines := strings.Count(output, "\n"); lines != records {
+		t.Errorf("%s: got %d records, want %d:\n%s", name, lines, records, output)
+	}
+	if !strings.Contains(output, redactedValue) {
+		t.Errorf("%s: no redacted value:\n%s", name, output)
+	}
+
+	for label, secret := range map[string]string{
+		"password":          "hunter2",
+		"ciphertext":        string(ciphertext),
+		"ciphertext base64": base64.StdEncoding.EncodeToString(ciphertext),
+		"ciphertext bytes":  fmt.Sprint(ciphertext),
+		"nonce":             string(nonce),
+		"nonce base64":      base64.StdEncoding.EncodeToString(nonce),
+		"nonce bytes":       fmt.Sprint(nonce),
+	} {
+		if strings.Contains(output, secret) {
+			t.Errorf("%s: %s leaked:\n%s", name, label, output)
+		}
+	}
+}
+
+func TestTracefPayloads(t *testing.T) {
+	var output bytes.Buffer
+	log := newLogger(Config{Format: TextFormat, Level: TraceLevel}, &output, nil, &outputs{})
+
+	// As traced by go-res for an incoming access request and its response
+	request := []byte(`{"token":{"sessionId":"hunter2"},"header":{"Cookie":["session=hunter2"]},"remoteAddr":"203.0.113.7:51234"}`)
+	response := []byte(`{"result":{"model":{"encryptedContent":"` + base64.StdEncoding.EncodeToString(ciphertext) + `"}}}`)
+	log.Tracef("==> %s: %s", "access.api.room.lobby", request)
+	log.Tracef("<== %s: %s", "access.api.room.lobby", response)
+
+	for _, want := range []string{
+		fmt.Sprintf("==> access.api.room.lobby: %s", byteCount(request)),
+		fmt.Sprintf("<== access.api.room.lobby: %s", byteCount(response)),
+	} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("no %q trace:\n%s", want, output.String())
+		}
+	}
+	for _, secret := range []string{"hunter2", "203.0.113.7", base64.StdEncoding.EncodeToString(ciphertext)} {
+		if strings.Contains(output.String(), secret) {
+			t.Errorf("%q leaked:\n%s", secret, output.String())
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// formatHandler formats the records as JSON or text depending on the current format, which can change at runtime.
+type formatHandler struct {
+	json   slog.Handler
+	text   slog.Handler
+	format *atomic.Pointer[Format]
+}
+
+func (h *formatHandler) current() slog.Handler {
+	if *h.format.Load() == JSONFormat {
+		return h.json
+	}
+	return h.text
+}
+
+func (h *formatHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.current().Enabled(ctx, level)
+}
+
+func (h *formatHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.current().Handle(ctx, r)
+}
+
+func (h *formatHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &formatHandler{json: h.json.WithAttrs(attrs), text: h.text.WithAttrs(attrs), format: h.format}
+}
+
+func (h *formatHandler) WithGroup(name string) slog.Handler {
+	return &formatHandler{json: h.json.WithGroup(name), text: h.text.WithGroup(name), format: h.format}
+}
+
+// Reconfigure changes the configured level and format of the logger and its children. A runtime level change still
+// pending keeps its level until reverted, to the new configured level.
+func (l *Logger) Reconfigure(level LogLevel, format Format) {
+	l.levels.setBaseline(convertLevel(level))
+	if l.outputs != nil {
+		l.outputs.format.Store(&format)
+	}
+}
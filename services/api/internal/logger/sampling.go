@@ -0,0 +1,133 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// SamplingConfig limits the records logged per level and message: in each interval, the first Initial records are
+// logged, then one in Thereafter. The number of suppressed records is logged when the interval ends.
+type SamplingConfig struct {
+	Initial    int
+	Thereafter int // Zero suppresses every record past the initial ones
+	Interval   time.Duration
+}
+
+type samplingKey struct {
+	level slog.Level
+	msg   string
+}
+
+type samplingCounter struct {
+	count      int
+	suppressed int
+}
+
+// sampler counts the records per key over the current interval. It is shared by a handler and its children, so that
+// the records of request-scoped loggers are sampled together.
+type sampler struct {
+	SamplingConfig
+	handler slog.Handler // Receives the summaries, without the attributes of the children
+
+	mu        sync.Mutex
+	counters  map[samplingKey]*samplingCounter
+	windowEnd time.Time
+	flush     *time.Timer
+}
+
+// samplingHandler drops the records rejected by its sampler.
+type samplingHandler struct {
+	slog.Handler
+	sampler *sampler
+}
+
+func newSamplingHandler(handler slog.Handler, cfg SamplingConfig) *samplingHandler {
+	return &samplingHandler{
+		Handler: handler,
+		sampler: &sampler{
+			SamplingConfig: cfg,
+			handler:        handler,
+			counters:       make(map[samplingKey]*samplingCounter),
+		},
+	}
+}
+
+func (h *samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if !h.sampler.allow(r.Level, r.Message) {
+		return nil
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{Handler: h.Handler.WithAttrs(attrs), sampler: h.sampler}
+}
+
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{Handler: h.Handler.WithGroup(name), sampler: h.sampler}
+}
+
+func (s *sampler) allow(level slog.Level, msg string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if !now.Before(s.windowEnd) {
+		s.resetLocked(now)
+	}
+
+	key := samplingKey{level: level, msg: msg}
+	counter, ok := s.counters[key]
+	if !ok {
+		counter = &samplingCounter{}
+		s.counters[key] = counter
+	}
+	counter.count++
+
+	if counter.count <= s.Initial {
+		return true
+	}
+	if s.Thereafter > 0 && (counter.count-s.Initial)%s.Thereafter == 0 {
+		return true
+	}
+
+	counter.suppressed++
+	if s.flush == nil {
+		s.flush = time.AfterFunc(s.windowEnd.Sub(now), s.flushSuppressed)
+	}
+	return false
+}
+
+// flushSuppressed ends the interval once expired, so that the summaries are logged even when the records stop.
+func (s *sampler) flushSuppressed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now := time.Now(); !now.Before(s.windowEnd) {
+		s.resetLocked(now)
+	}
+}
+
+// resetLocked logs the summaries of the ending interval and starts a new one.
+func (s *sampler) resetLocked(now time.Time) {
+	if s.flush != nil {
+		s.flush.Stop()
+		s.flush = nil
+	}
+
+	for key, counter := range s.counters {
+		if counter.suppressed == 0 {
+			continue
+		}
+
+		record := slog.NewRecord(now, key.level, "Suppressed duplicate log records", 0)
+		record.AddAttrs(slog.String("message", key.msg), slog.Int("suppressed", counter.suppressed))
+		_ = s.handler.Handle(context.Background(), record)
+	}
+
+	clear(s.counters)
+	s.windowEnd = now.Add(s.Interval)
+}
@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"github.com/Autherain/go_cyber/internal/health"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RegisterHealth exposes the results of the health checks, read with statusFunc on each scrape. The health checker
+// caches its results, so scrapes do not run the checks more often than its interval.
+func (m *Metrics) RegisterHealth(statusFunc func() health.Status) {
+	m.registry.MustRegister(&healthCollector{statusFunc: statusFunc})
+}
+
+var (
+	healthCheckUpDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "health", "check_up"),
+		"Whether the health check passes (1) or fails (0).",
+		[]string{"check", "optional"}, nil,
+	)
+	healthCheckLatencyDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "health", "check_latency_seconds"),
+		"Latency of the latest run of the health check.",
+		[]string{"check"}, nil,
+	)
+	healthReadyDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "health", "ready"),
+		"Whether the service is ready (1) or not (0).",
+		nil, nil,
+	)
+)
+
+type healthCollector struct {
+	statusFunc func() health.Status
+}
+
+func (c *healthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- healthCheckUpDesc
+	ch <- healthCheckLatencyDesc
+	ch <- healthReadyDesc
+}
+
+func (c *healthCollector) Collect(ch chan<- prometheus.Metric) {
+	status := c.statusFunc()
+
+	for name, result := range status.Checks {
+		ch <- prometheus.MustNewConstMetric(
+			healthCheckUpDesc, prometheus.GaugeValue,
+			boolValue(result.Status == health.StatusHealthy), name, boolLabel(result.Optional),
+		)
+		ch <- prometheus.MustNewConstMetric(
+			healthCheckLatencyDesc, prometheus.GaugeValue, result.Latency.Seconds(), name,
+		)
+	}
+	ch <- prometheus.MustNewConstMetric(healthReadyDesc, prometheus.GaugeValue, boolValue(status.Ready))
+}
+
+var _ prometheus.Collector = (*healthCollector)(nil)
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func boolLabel(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
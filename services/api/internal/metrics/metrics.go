@@ -0,0 +1,168 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "api"
+
+	activeRoomsTimeout = 5 * time.Second
+)
+
+// Metrics holds the Prometheus collectors of the service and serves them over HTTP
+type Metrics struct {
+	registry *prometheus.Registry
+
+	resRequests        *prometheus.CounterVec
+	resRequestDuration *prometheus.HistogramVec
+	storeQueryDuration *prometheus.HistogramVec
+	natsDisconnects    prometheus.Counter
+	natsReconnects     prometheus.Counter
+
+	connections *connectionTracker
+}
+
+// New creates the service metrics, along with the Go runtime and process collectors
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		resRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "res",
+			Name:      "requests_total",
+			Help:      "RES requests handled, by resource pattern, request type and method.",
+		}, []string{"pattern", "type", "method"}),
+		resRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "res",
+			Name:      "request_duration_seconds",
+			Help:      "RES request handling latency, by resource pattern, request type and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"pattern", "type", "method"}),
+		storeQueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "store",
+			Name:      "query_duration_seconds",
+			Help:      "Store query latency, by store method and outcome.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "outcome"}),
+		natsDisconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "nats",
+			Name:      "disconnects_total",
+			Help:      "NATS connection losses.",
+		}),
+		natsReconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "nats",
+			Name:      "reconnects_total",
+			Help:      "NATS reconnections.",
+		}),
+		connections: newConnectionTracker(activeConnectionWindow),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.resRequests,
+		m.resRequestDuration,
+		m.storeQueryDuration,
+		m.natsDisconnects,
+		m.natsReconnects,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "res",
+			Name:      "active_connections",
+			Help:      "Client connections (participants) that made a RES request within the last " + activeConnectionWindow.String() + ".",
+		}, func() float64 { return float64(m.connections.count(time.Now())) }),
+	)
+
+	return m
+}
+
+// Handler returns the HTTP handler serving the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RegisterActiveRooms exposes the number of active rooms, read with countFunc on each scrape
+func (m *Metrics) RegisterActiveRooms(countFunc func(ctx context.Context) (int64, error)) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_rooms",
+		Help:      "Rooms currently active.",
+	}, func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), activeRoomsTimeout)
+		defer cancel()
+
+		count, err := countFunc(ctx)
+		if err != nil {
+			return 0
+		}
+		return float64(count)
+	}))
+}
+
+// ObserveQuery records the latency of a store method
+func (m *Metrics) ObserveQuery(method string, duration time.Duration, err error) {
+	m.storeQueryDuration.WithLabelValues(method, outcome(err)).Observe(duration.Seconds())
+}
+
+// NATSDisconnected counts a NATS connection loss
+func (m *Metrics) NATSDisconnected(error) {
+	m.natsDisconnects.Inc()
+}
+
+// NATSReconnected counts a NATS reconnection
+func (m *Metrics) NATSReconnected() {
+	m.natsReconnects.Inc()
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
+
+// activeConnectionWindow is how long a connection counts as active after its last request. Resgate does not notify
+// services of disconnections, so connected participants are approximated by recently active connections.
+const activeConnectionWindow = 5 * time.Minute
+
+// connectionTracker remembers when each connection was last seen
+type connectionTracker struct {
+	mu       sync.Mutex
+	lastSeen map[string]time.Time
+	window   time.Duration
+}
+
+func newConnectionTracker(window time.Duration) *connectionTracker {
+	return &connectionTracker{lastSeen: make(map[string]time.Time), window: window}
+}
+
+func (t *connectionTracker) seen(cid string, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastSeen[cid] = at
+}
+
+// count returns the number of active connections, forgetting the inactive ones
+func (t *connectionTracker) count(now time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for cid, lastSeen := range t.lastSeen {
+		if now.Sub(lastSeen) > t.window {
+			delete(t.lastSeen, cid)
+		}
+	}
+	return len(t.lastSeen)
+}
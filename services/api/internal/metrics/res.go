@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/jirenius/go-res"
+)
+
+// Request types, as used in the type label of the RES metrics
+const (
+	requestTypeAccess = "access"
+	requestTypeGet    = "get"
+	requestTypeCall   = "call"
+	requestTypeAuth   = "auth"
+)
+
+// InstrumentRES returns a RES handler option recording the requests handled for the given resource pattern. It must be
+// given after the options setting the handlers it instruments.
+func (m *Metrics) InstrumentRES(pattern string) res.Option {
+	return res.OptionFunc(func(h *res.Handler) {
+		if access := h.Access; access != nil {
+			h.Access = func(r res.AccessRequest) {
+				m.connections.seen(r.CID(), time.Now())
+				defer m.observeRequest(pattern, requestTypeAccess, "", time.Now())
+				access(r)
+			}
+		}
+
+		if get := h.Get; get != nil {
+			h.Get = func(r res.GetRequest) {
+				defer m.observeRequest(pattern, requestTypeGet, "", time.Now())
+				get(r)
+			}
+		}
+
+		for method, call := range h.Call {
+			h.Call[method] = func(r res.CallRequest) {
+				m.connections.seen(r.CID(), time.Now())
+				defer m.observeRequest(pattern, requestTypeCall, method, time.Now())
+				call(r)
+			}
+		}
+
+		for method, auth := range h.Auth {
+			h.Auth[method] = func(r res.AuthRequest) {
+				m.connections.seen(r.CID(), time.Now())
+				defer m.observeRequest(pattern, requestTypeAuth, method, time.Now())
+				auth(r)
+			}
+		}
+	})
+}
+
+func (m *Metrics) observeRequest(pattern, requestType, method string, start time.Time) {
+	m.resRequests.WithLabelValues(pattern, requestType, method).Inc()
+	m.resRequestDuration.WithLabelValues(pattern, requestType, method).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,128 @@
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Direction of a keyset page relative to the key carried by a cursor.
+type Direction string
+
+const (
+	// After selects the items following the cursor key.
+	After Direction = "after"
+	// Before selects the items preceding the cursor key.
+	Before Direction = "before"
+)
+
+// Valid reports whether the direction is one of the known directions.
+func (d Direction) Valid() bool { return d == After || d == Before }
+
+// Cursor is the decoded content of an opaque cursor token. Key holds the components of a (possibly composite) keyset
+// key, in the order they are compared.
+type Cursor struct {
+	Key       []string  `json:"k"`
+	Direction Direction `json:"d"`
+}
+
+var (
+	ErrInvalidCursor  = errors.New("invalid cursor")
+	ErrTamperedCursor = errors.New("tampered cursor")
+)
+
+const cursorSignatureSeparator = "."
+
+var cursorEncoding = base64.RawURLEncoding
+
+// CursorCodec encodes cursors into opaque, base64url-encoded tokens and decodes them back. When a signing key is
+// configured, tokens carry an HMAC-SHA256 signature and unsigned or tampered tokens are rejected.
+//
+// A nil *CursorCodec is valid and behaves as an unsigned codec.
+type CursorCodec struct {
+	signingKey []byte
+}
+
+// NewCursorCodec creates a cursor codec with the given options.
+func NewCursorCodec(options ...cursorCodecOption) *CursorCodec {
+	codec := &CursorCodec{}
+	for _, option := range options {
+		option(codec)
+	}
+
+	return codec
+}
+
+type cursorCodecOption func(*CursorCodec)
+
+// WithSigningKey signs the tokens produced by the codec with the given HMAC key.
+func WithSigningKey(key []byte) cursorCodecOption {
+	return func(c *CursorCodec) { c.signingKey = key }
+}
+
+// Encode the cursor into an opaque token.
+func (c *CursorCodec) Encode(cursor Cursor) (string, error) {
+	if !cursor.Direction.Valid() {
+		return "", fmt.Errorf("%w: unknown direction %q", ErrInvalidCursor, cursor.Direction)
+	}
+
+	payload, err := json.Marshal(cursor)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	token := cursorEncoding.EncodeToString(payload)
+	if !c.signed() {
+		return token, nil
+	}
+
+	return token + cursorSignatureSeparator + cursorEncoding.EncodeToString(c.sign(token)), nil
+}
+
+// Decode an opaque token into a cursor, verifying its signature when the codec has a signing key.
+func (c *CursorCodec) Decode(token string) (Cursor, error) {
+	encodedPayload, encodedSignature, hasSignature := strings.Cut(token, cursorSignatureSeparator)
+
+	switch {
+	case c.signed() && !hasSignature:
+		return Cursor{}, fmt.Errorf("%w: missing signature", ErrTamperedCursor)
+	case !c.signed() && hasSignature:
+		return Cursor{}, fmt.Errorf("%w: unexpected signature", ErrInvalidCursor)
+	}
+
+	if hasSignature {
+		signature, err := cursorEncoding.DecodeString(encodedSignature)
+		if err != nil || !hmac.Equal(signature, c.sign(encodedPayload)) {
+			return Cursor{}, ErrTamperedCursor
+		}
+	}
+
+	payload, err := cursorEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return Cursor{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	if !cursor.Direction.Valid() {
+		return Cursor{}, fmt.Errorf("%w: unknown direction %q", ErrInvalidCursor, cursor.Direction)
+	}
+
+	return cursor, nil
+}
+
+func (c *CursorCodec) signed() bool { return c != nil && len(c.signingKey) > 0 }
+
+func (c *CursorCodec) sign(encodedPayload string) []byte {
+	mac := hmac.New(sha256.New, c.signingKey)
+	mac.Write([]byte(encodedPayload))
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,95 @@
+package pagination_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Autherain/go_cyber/internal/pagination"
+)
+
+var cursor = pagination.Cursor{Key: []string{"2025-02-01T16:49:57Z", "0194c3a2-5f1e-7c3b-9a2d-1e4f5a6b7c8d"}, Direction: pagination.Before}
+
+func TestCursorRoundTrip(t *testing.T) {
+	for name, codec := range map[string]*pagination.CursorCodec{
+		"unsigned": pagination.NewCursorCodec(),
+		"nil":      nil,
+		"signed":   pagination.NewCursorCodec(pagination.WithSigningKey([]byte("key"))),
+	} {
+		t.Run(name, func(t *testing.T) {
+			token, err := codec.Encode(cursor)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if strings.Contains(token, cursor.Key[1]) {
+				t.Errorf("token %q is not opaque", token)
+			}
+
+			decoded, err := codec.Decode(token)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if !reflect.DeepEqual(decoded, cursor) {
+				t.Errorf("Decode() = %+v, want %+v", decoded, cursor)
+			}
+		})
+	}
+}
+
+func TestCursorDecodeRejects(t *testing.T) {
+	signed := pagination.NewCursorCodec(pagination.WithSigningKey([]byte("key")))
+	unsigned := pagination.NewCursorCodec()
+
+	signedToken, err := signed.Encode(cursor)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	unsignedToken, err := unsigned.Encode(cursor)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	foreignToken, err := pagination.NewCursorCodec(pagination.WithSigningKey([]byte("other"))).Encode(cursor)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	payload, signature, _ := strings.Cut(signedToken, ".")
+	flipped := []byte(payload)
+	flipped[len(flipped)/2] ^= 'A' ^ 'B'
+	if flipped[len(flipped)/2] == payload[len(payload)/2] {
+		t.Fatal("payload byte not flipped")
+	}
+
+	invalidDirection := base64.RawURLEncoding.EncodeToString([]byte(`{"k":["1"],"d":"sideways"}`))
+
+	tests := []struct {
+		name  string
+		codec *pagination.CursorCodec
+		token string
+		want  error
+	}{
+		{"flipped payload byte", signed, string(flipped) + "." + signature, pagination.ErrTamperedCursor},
+		{"stripped signature", signed, payload, pagination.ErrTamperedCursor},
+		{"unsigned token", signed, unsignedToken, pagination.ErrTamperedCursor},
+		{"foreign signature", signed, foreignToken, pagination.ErrTamperedCursor},
+		{"unexpected signature", unsigned, signedToken, pagination.ErrInvalidCursor},
+		{"invalid direction", unsigned, invalidDirection, pagination.ErrInvalidCursor},
+		{"invalid encoding", unsigned, "not base64!", pagination.ErrInvalidCursor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.codec.Decode(tt.token); !errors.Is(err, tt.want) {
+				t.Errorf("Decode() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursorEncodeRejectsInvalidDirection(t *testing.T) {
+	_, err := pagination.NewCursorCodec().Encode(pagination.Cursor{Key: []string{"1"}, Direction: "sideways"})
+	if !errors.Is(err, pagination.ErrInvalidCursor) {
+		t.Errorf("Encode() error = %v, want %v", err, pagination.ErrInvalidCursor)
+	}
+}
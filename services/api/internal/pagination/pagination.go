@@ -1,8 +1,11 @@
 package pagination
 
 import (
+	"cmp"
+	"context"
 	"errors"
 	"fmt"
+	"iter"
 	"net/url"
 	"strconv"
 )
@@ -12,8 +15,10 @@ type Pager[S ~[]E, E any] struct {
 
 	lastErr  error // The last error that happened while reading a page.
 	lastPage S     // The most recent page read.
+	pending  []S   // Pages read ahead by a prefetching iterator but not yielded, returned first by Next.
 
 	allowShorterPages bool
+	prefetch          bool
 	size              int
 }
 
@@ -32,6 +37,7 @@ func NewPager[S ~[]E, E any](reader pageReader[S, E], options ...pagerOption) *P
 	return &Pager[S, E]{
 		reader:            reader,
 		allowShorterPages: configuration.allowShorterPages,
+		prefetch:          configuration.prefetch,
 		size:              configuration.size,
 	}
 }
@@ -41,6 +47,12 @@ func AllowShorterPages() pagerOption {
 	return func(c *pagerConfiguration) { c.allowShorterPages = true }
 }
 
+// WithPrefetch makes the iterators returned by [Pager.Pages] and [Pager.All] read the next page concurrently while the
+// caller processes the current one.
+func WithPrefetch() pagerOption {
+	return func(c *pagerConfiguration) { c.prefetch = true }
+}
+
 // WithPageSize sets the size of the pages to be read by the pager.
 func WithPageSize(size int) pagerOption { return func(c *pagerConfiguration) { c.size = size } }
 
@@ -51,6 +63,11 @@ func (p *Pager[S, E]) Err() error { return p.lastErr }
 // is no next page or an error happened while preparing it. [Pager.Err] should be called to distinguish between the two
 // cases.
 func (p *Pager[S, E]) Next() bool {
+	if len(p.pending) > 0 {
+		p.lastPage, p.pending = p.pending[0], p.pending[1:]
+		return true
+	}
+
 	if !p.allowShorterPages {
 		// If the last page is shorter than the size, there are no more pages.
 		if p.lastPage != nil && len(p.lastPage) < p.size {
@@ -81,11 +98,115 @@ func (p *Pager[S, E]) Reset() {
 	p.reader.reset()
 	p.lastErr = nil
 	p.lastPage = nil
+	p.pending = nil
+}
+
+// Pages returns an iterator over the pages of the pager, starting from its current position. Iteration stops after the
+// last page, or after yielding the error encountered while reading a page or the error of the context once it is done.
+//
+// In prefetch mode (see [WithPrefetch]), [Pager.Page] must not be called while iterating as the pager is concurrently
+// reading ahead.
+func (p *Pager[S, E]) Pages(ctx context.Context) iter.Seq2[S, error] {
+	return func(yield func(S, error) bool) {
+		if p.prefetch {
+			p.prefetchPages(ctx, yield)
+			return
+		}
+
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if !p.Next() {
+				if err := p.Err(); err != nil {
+					yield(nil, err)
+				}
+				return
+			}
+
+			if !yield(p.Page(), nil) {
+				return
+			}
+		}
+	}
+}
+
+// All returns an iterator over the items of every page of the pager. Errors are reported as in [Pager.Pages], along
+// with the zero value of E.
+func (p *Pager[S, E]) All(ctx context.Context) iter.Seq2[E, error] {
+	return func(yield func(E, error) bool) {
+		for page, err := range p.Pages(ctx) {
+			if err != nil {
+				var zero E
+				yield(zero, err)
+				return
+			}
+
+			for _, item := range page {
+				if !yield(item, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+type pageResult[S any] struct {
+	page S
+	err  error
+}
+
+// prefetchPages reads pages in a separate goroutine, one page ahead of the caller. The reading goroutine is always
+// waited for before returning so the pager can safely be reused afterwards, and the pages it read but that were not
+// yielded are kept pending, so the pager resumes where the caller stopped.
+func (p *Pager[S, E]) prefetchPages(ctx context.Context, yield func(S, error) bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	results := make(chan pageResult[S], 1)
+
+	go func() {
+		defer close(results)
+
+		for ctx.Err() == nil && p.Next() {
+			results <- pageResult[S]{page: p.Page()}
+		}
+
+		if err := cmp.Or(ctx.Err(), p.Err()); err != nil {
+			results <- pageResult[S]{err: err}
+		}
+	}()
+
+	var unread []S
+	defer func() {
+		cancel()
+		// Drain until the reading goroutine exits, before touching the pending pages it reads from.
+		for result := range results {
+			if result.err == nil {
+				unread = append(unread, result.page)
+			}
+		}
+		p.pending = append(unread, p.pending...)
+	}()
+
+	for result := range results {
+		// Pages read before the context was done are not yielded after it
+		if err := ctx.Err(); err != nil && result.err == nil {
+			unread = append(unread, result.page)
+			yield(nil, err)
+			return
+		}
+
+		if !yield(result.page, result.err) || result.err != nil {
+			return
+		}
+	}
 }
 
 type pagerConfiguration struct {
 	size              int
 	allowShorterPages bool
+	prefetch          bool
 }
 
 type pagerOption func(*pagerConfiguration)
@@ -149,41 +270,130 @@ func (r *offsetPagerReader[S, E]) readPage(size int) (S, error) {
 func (r *offsetPagerReader[S, E]) reset() { r.offset = 0 }
 
 const (
-	keysetSelectorLastKeyQuery = "lastKey"
-	keysetSelectorSizeQuery    = "size"
+	keysetSelectorCursorQuery = "cursor"
+	keysetSelectorSizeQuery   = "size"
 )
 
+// KeysetSelector selects a page of a keyset-paginated collection: the Size items found in Direction from Key.
 type KeysetSelector[T any] struct {
-	LastKey T
-	Size    int
+	Key       T
+	Direction Direction
+	Size      int
+}
+
+// Cursor encodes the key and direction of the selector into an opaque cursor token, using formatKeyFunc to split the
+// key into its cursor components.
+func (s *KeysetSelector[T]) Cursor(codec *CursorCodec, formatKeyFunc func(key T) []string) (string, error) {
+	direction := s.Direction
+	if direction == "" {
+		direction = After
+	}
+
+	return codec.Encode(Cursor{Key: formatKeyFunc(s.Key), Direction: direction})
+}
+
+// Next returns the selector of the page following the one ending with the given key, in the same direction.
+func (s *KeysetSelector[T]) Next(key T) *KeysetSelector[T] {
+	return &KeysetSelector[T]{Key: key, Direction: s.Direction, Size: s.Size}
 }
 
-func (s *KeysetSelector[T]) Query() url.Values {
+// Query encodes the selector into query values, using formatKeyFunc to split the key into its cursor components.
+func (s *KeysetSelector[T]) Query(codec *CursorCodec, formatKeyFunc func(key T) []string) (url.Values, error) {
+	cursor, err := s.Cursor(codec, formatKeyFunc)
+	if err != nil {
+		return nil, err
+	}
+
 	result := url.Values{}
-	result.Add(keysetSelectorLastKeyQuery, fmt.Sprint(s.LastKey))
+	result.Add(keysetSelectorCursorQuery, cursor)
 	result.Add(keysetSelectorSizeQuery, strconv.Itoa(s.Size))
 
-	return result
+	return result, nil
 }
 
-var (
-	ErrInvalidLastKey = errors.New("invalid lastKey query parameter")
-	ErrInvalidSize    = errors.New("invalid size query parameter")
-)
+// PageInfo describes a keyset page relative to the rest of its collection. NextKey is the key to pass to
+// [KeysetSelector.Next] to read the following page, and is only meaningful when HasMore is true. EstimatedTotal is nil
+// unless an estimate of the collection size was requested.
+type PageInfo[K any] struct {
+	HasMore        bool
+	NextKey        K
+	EstimatedTotal *int64
+}
+
+var ErrInvalidSize = errors.New("invalid size query parameter")
+
+// SizeOutOfRangeError is returned by [ParseKeysetSelector] when the requested size is outside of the [SizePolicy].
+type SizeOutOfRangeError struct {
+	Size int
+	Min  int
+	Max  int
+}
+
+func (e *SizeOutOfRangeError) Error() string {
+	return fmt.Sprintf("%v: %d is not between %d and %d", ErrInvalidSize, e.Size, e.Min, e.Max)
+}
+
+func (e *SizeOutOfRangeError) Unwrap() error { return ErrInvalidSize }
+
+// SizePolicy bounds the page sizes accepted by [ParseKeysetSelector]. Default is used when no size is requested.
+type SizePolicy struct {
+	Default int
+	Min     int
+	Max     int
+}
+
+// DefaultSizePolicy is the policy used when none is given to [ParseKeysetSelector].
+var DefaultSizePolicy = SizePolicy{Default: 25, Min: 1, Max: 100}
+
+func (p SizePolicy) check(size int) error {
+	if size < p.Min || size > p.Max {
+		return &SizeOutOfRangeError{Size: size, Min: p.Min, Max: p.Max}
+	}
+
+	return nil
+}
+
+type selectorConfiguration struct {
+	sizePolicy SizePolicy
+}
 
+type selectorOption func(*selectorConfiguration)
+
+// WithSizePolicy sets the page size policy applied while parsing a selector.
+func WithSizePolicy(policy SizePolicy) selectorOption {
+	return func(c *selectorConfiguration) { c.sizePolicy = policy }
+}
+
+// ParseKeysetSelector parses the cursor and size query parameters. The cursor is decoded (and its signature verified)
+// with the given codec, then parseKeyFunc rebuilds the key from the cursor components. Without a cursor, the selector
+// starts from the zero key in the [After] direction. The size is checked against the [SizePolicy], which defaults to
+// [DefaultSizePolicy].
 func ParseKeysetSelector[T any](
 	query url.Values,
-	parseLastKeyFunc func(key string) (T, error),
+	codec *CursorCodec,
+	parseKeyFunc func(key []string) (T, error),
+	options ...selectorOption,
 ) (*KeysetSelector[T], error) {
-	result := &KeysetSelector[T]{}
+	configuration := &selectorConfiguration{sizePolicy: DefaultSizePolicy}
+	for _, option := range options {
+		option(configuration)
+	}
+
+	result := &KeysetSelector[T]{Direction: After, Size: configuration.sizePolicy.Default}
+
+	if cursorQuery := query.Get(keysetSelectorCursorQuery); cursorQuery != "" {
+		cursor, err := codec.Decode(cursorQuery)
+		if err != nil {
+			return nil, err
+		}
 
-	if lastKeyQuery := query.Get(keysetSelectorLastKeyQuery); lastKeyQuery != "" {
-		lastKey, err := parseLastKeyFunc(lastKeyQuery)
+		key, err := parseKeyFunc(cursor.Key)
 		if err != nil {
-			return nil, fmt.Errorf("%w: %v", ErrInvalidLastKey, err)
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
 		}
 
-		result.LastKey = lastKey
+		result.Key = key
+		result.Direction = cursor.Direction
 	}
 
 	if sizeQuery := query.Get(keysetSelectorSizeQuery); sizeQuery != "" {
@@ -192,6 +402,10 @@ func ParseKeysetSelector[T any](
 			return nil, fmt.Errorf("%w: %v", ErrInvalidSize, err)
 		}
 
+		if err := configuration.sizePolicy.check(size); err != nil {
+			return nil, err
+		}
+
 		result.Size = size
 	}
 
@@ -0,0 +1,78 @@
+package pagination_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/Autherain/go_cyber/internal/pagination"
+)
+
+// newPagers returns pagers over ten items in pages of three, reading sequentially or with prefetch.
+func newPagers() map[string]*pagination.Pager[[]int, int] {
+	items := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	newReader := func() func(size, offset int) ([]int, error) {
+		return func(size, offset int) ([]int, error) {
+			return items[min(offset, len(items)):min(offset+size, len(items))], nil
+		}
+	}
+
+	return map[string]*pagination.Pager[[]int, int]{
+		"sequential": pagination.NewPager[[]int](pagination.NewOffsetPageReader(newReader()),
+			pagination.WithPageSize(3), pagination.AllowShorterPages()),
+		"prefetch": pagination.NewPager[[]int](pagination.NewOffsetPageReader(newReader()),
+			pagination.WithPageSize(3), pagination.AllowShorterPages(), pagination.WithPrefetch()),
+	}
+}
+
+func TestPagerResumesAfterEarlyBreak(t *testing.T) {
+	for name, pager := range newPagers() {
+		t.Run(name, func(t *testing.T) {
+			for item, err := range pager.All(context.Background()) {
+				if err != nil {
+					t.Fatalf("All() error = %v", err)
+				}
+				if item == 4 {
+					break
+				}
+			}
+
+			var rest []int
+			for item, err := range pager.All(context.Background()) {
+				if err != nil {
+					t.Fatalf("All() error = %v", err)
+				}
+				rest = append(rest, item)
+			}
+
+			// The rest of the page of the break is skipped, as when calling Next
+			if want := []int{6, 7, 8, 9}; !slices.Equal(rest, want) {
+				t.Errorf("resumed at %v, want %v", rest, want)
+			}
+		})
+	}
+}
+
+func TestPagerStopsOnCancel(t *testing.T) {
+	for name, pager := range newPagers() {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var pages int
+			var err error
+			for _, err = range pager.Pages(ctx) {
+				if err != nil {
+					break
+				}
+				pages++
+				cancel()
+			}
+
+			if pages != 1 || !errors.Is(err, context.Canceled) {
+				t.Errorf("got %d pages then error %v, want 1 page then %v", pages, err, context.Canceled)
+			}
+		})
+	}
+}
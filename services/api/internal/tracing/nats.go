@@ -0,0 +1,41 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// NATSHeaderCarrier adapts NATS message headers to the OpenTelemetry propagation API
+type NATSHeaderCarrier nats.Header
+
+func (c NATSHeaderCarrier) Get(key string) string { return nats.Header(c).Get(key) }
+func (c NATSHeaderCarrier) Set(key, value string) { nats.Header(c).Set(key, value) }
+
+func (c NATSHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+var _ propagation.TextMapCarrier = NATSHeaderCarrier(nil)
+
+// ExtractNATS returns the context carrying the trace context found in the headers of the message, if any
+func ExtractNATS(ctx context.Context, msg *nats.Msg) context.Context {
+	if msg.Header == nil {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, NATSHeaderCarrier(msg.Header))
+}
+
+// InjectNATS writes the trace context of ctx into the headers of the message
+func InjectNATS(ctx context.Context, msg *nats.Msg) {
+	if msg.Header == nil {
+		msg.Header = nats.Header{}
+	}
+	otel.GetTextMapPropagator().Inject(ctx, NATSHeaderCarrier(msg.Header))
+}
@@ -0,0 +1,49 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jirenius/go-res"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StartRESSpan starts the root server span of a RES request. The HTTP headers of access, call and auth requests are
+// the ones the client sent when connecting to Resgate, not headers of the request, so a trace context found there is
+// only linked to, instead of every request of the connection becoming its child.
+func StartRESSpan(ctx context.Context, r res.Resource, requestType, method string) (context.Context, trace.Span) {
+	attributes := []attribute.KeyValue{
+		attribute.String("rpc.system", "res"),
+		attribute.String("res.resource", r.ResourceName()),
+		attribute.String("res.type", requestType),
+	}
+	if method != "" {
+		attributes = append(attributes, attribute.String("res.method", method))
+	}
+
+	options := []trace.SpanStartOption{trace.WithNewRoot(), trace.WithSpanKind(trace.SpanKindServer)}
+	if req, ok := r.(interface {
+		CID() string
+		Header() map[string][]string
+	}); ok {
+		attributes = append(attributes, attribute.String("res.cid", req.CID()))
+
+		connectCtx := otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(http.Header(req.Header())))
+		if connect := trace.SpanContextFromContext(connectCtx); connect.IsValid() {
+			options = append(options, trace.WithLinks(trace.Link{
+				SpanContext: connect,
+				Attributes:  []attribute.KeyValue{attribute.String("res.link", "connect")},
+			}))
+		}
+	}
+
+	name := "res." + requestType
+	if method != "" {
+		name += "." + method
+	}
+
+	return Tracer().Start(ctx, name, append(options, trace.WithAttributes(attributes...))...)
+}
@@ -1,6 +1,10 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/Autherain/go_cyber/internal/pagination"
@@ -8,19 +12,67 @@ import (
 )
 
 type Message struct {
-	ID                uuid.UUID
-	RoomID            uuid.UUID
-	encrypted_content byte
-	nonce             byte
-	timestamp         time.Time
+	ID               uuid.UUID `json:"id"`
+	RoomID           uuid.UUID `json:"roomId"`
+	EncryptedContent []byte    `json:"encryptedContent"`
+	Nonce            []byte    `json:"nonce"`
+	Timestamp        time.Time `json:"timestamp"`
+}
+
+// LogValue logs the message metadata, the logger redacting its ciphertext and nonce.
+func (m Message) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("id", m.ID.String()),
+		slog.String("roomId", m.RoomID.String()),
+		slog.Any("encryptedContent", m.EncryptedContent),
+		slog.Any("nonce", m.Nonce),
+		slog.Time("timestamp", m.Timestamp),
+	)
+}
+
+// MessageKey is the keyset pagination key of messages, which are ordered by timestamp then ID.
+type MessageKey struct {
+	Timestamp time.Time
+	ID        uuid.UUID
+}
+
+// IsZero reports whether the key is the zero key, which selects from the start of the room history.
+func (k MessageKey) IsZero() bool { return k.Timestamp.IsZero() && k.ID == uuid.Nil }
+
+// FormatMessageKey splits the key into its cursor components.
+func FormatMessageKey(key MessageKey) []string {
+	return []string{key.Timestamp.UTC().Format(time.RFC3339Nano), key.ID.String()}
+}
+
+var errInvalidMessageKey = errors.New("message key requires a timestamp and an ID")
+
+// ParseMessageKey rebuilds a key from the cursor components produced by FormatMessageKey.
+func ParseMessageKey(key []string) (MessageKey, error) {
+	const keyParts = 2
+	if len(key) != keyParts {
+		return MessageKey{}, errInvalidMessageKey
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, key[0])
+	if err != nil {
+		return MessageKey{}, fmt.Errorf("invalid message key timestamp: %w", err)
+	}
+
+	id, err := uuid.FromString(key[1])
+	if err != nil {
+		return MessageKey{}, fmt.Errorf("invalid message key ID: %w", err)
+	}
+
+	return MessageKey{Timestamp: timestamp, ID: id}, nil
 }
 
 type MessagesSelector struct {
-	*pagination.KeysetSelector[uuid.UUID]
+	*pagination.KeysetSelector[MessageKey]
 
-	RoomID uuid.UUID
+	RoomID        uuid.UUID
+	EstimateTotal bool // Whether to estimate the number of messages in the room from the database statistics.
 }
 
 type MessageManager interface {
-	ReadMessages(selector *MessagesSelector) (*[]Message, error)
+	ReadMessages(ctx context.Context, selector *MessagesSelector) (*[]Message, *pagination.PageInfo[MessageKey], error)
 }
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	api "github.com/Autherain/go_cyber"
+	"github.com/Autherain/go_cyber/internal/logger"
+	"github.com/Autherain/go_cyber/internal/pagination"
+	"github.com/Autherain/go_cyber/internal/tracing"
+	"github.com/gofrs/uuid"
+	"github.com/jirenius/go-res"
+)
+
+const (
+	// totalQuery requests an estimate of the collection size when set to totalEstimated.
+	totalQuery     = "total"
+	totalEstimated = "estimated"
+)
+
+// roomMessagesPage is the page read for a room messages query, along with its normalized query.
+type roomMessagesPage struct {
+	messages []api.Message
+	info     *pagination.PageInfo[api.MessageKey]
+	selector *api.MessagesSelector
+	query    url.Values
+}
+
+// pageModel is the companion model of a paginated query collection, describing the page returned for the same query.
+type pageModel struct {
+	HasMore        bool   `json:"hasMore"`
+	NextCursor     string `json:"nextCursor,omitempty"`
+	EstimatedTotal *int64 `json:"estimatedTotal,omitempty"`
+}
+
+var errInvalidQuery = errors.New("invalid query")
+
+// handleRoomMessagesRequest serves the room history as a query collection paginated with the cursor and size query
+// parameters.
+func (s *Server) handleRoomMessagesRequest() res.Option {
+	return res.GetCollection(func(r res.CollectionRequest) {
+		ctx, span := s.startRequest(r, requestTypeGet, "")
+		var err error
+		defer func() { tracing.End(span, err) }()
+
+		page, err := s.readRoomMessagesPage(ctx, r)
+		if err != nil {
+			replyPageError(r, err)
+			return
+		}
+
+		collection := make([]res.DataValue[api.Message], 0, len(page.messages))
+		for _, message := range page.messages {
+			collection = append(collection, res.NewDataValue(message))
+		}
+
+		r.QueryCollection(collection, page.query.Encode())
+	})
+}
+
+// handleRoomMessagesPageRequest serves the companion model of the room messages collection, which takes the same query.
+func (s *Server) handleRoomMessagesPageRequest() res.Option {
+	return res.GetModel(func(r res.ModelRequest) {
+		ctx, span := s.startRequest(r, requestTypeGet, "")
+		var err error
+		defer func() { tracing.End(span, err) }()
+
+		page, err := s.readRoomMessagesPage(ctx, r)
+		if err != nil {
+			replyPageError(r, err)
+			return
+		}
+
+		model := pageModel{HasMore: page.info.HasMore, EstimatedTotal: page.info.EstimatedTotal}
+		if page.info.HasMore {
+			model.NextCursor, err = page.selector.Next(page.info.NextKey).Cursor(s.cursorCodec, api.FormatMessageKey)
+			if err != nil {
+				logger.FromContext(ctx).Error("Could not encode next cursor", "error", err)
+				replyPageError(r, err)
+				return
+			}
+		}
+
+		r.QueryModel(model, page.query.Encode())
+	})
+}
+
+func (s *Server) readRoomMessagesPage(ctx context.Context, r res.Resource) (*roomMessagesPage, error) {
+	roomID, err := uuid.FromString(r.PathParam("roomID"))
+	if err != nil {
+		return nil, res.ErrNotFound
+	}
+
+	query := r.ParseQuery()
+
+	keyset, err := pagination.ParseKeysetSelector(
+		query,
+		s.cursorCodec,
+		api.ParseMessageKey,
+		pagination.WithSizePolicy(s.pageSizePolicy),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidQuery, err)
+	}
+
+	selector := &api.MessagesSelector{
+		KeysetSelector: keyset,
+		RoomID:         roomID,
+		EstimateTotal:  query.Get(totalQuery) == totalEstimated,
+	}
+
+	messages, info, err := s.store.Messages.ReadMessages(ctx, selector)
+	if err != nil {
+		logger.FromContext(ctx).Error("Could not read room messages", "room", roomID, "error", err)
+		return nil, err
+	}
+
+	normalizedQuery, err := keyset.Query(s.cursorCodec, api.FormatMessageKey)
+	if err != nil {
+		logger.FromContext(ctx).Error("Could not encode cursor", "error", err)
+		return nil, err
+	}
+	if selector.EstimateTotal {
+		normalizedQuery.Set(totalQuery, totalEstimated)
+	}
+
+	return &roomMessagesPage{messages: *messages, info: info, selector: selector, query: normalizedQuery}, nil
+}
+
+// pageErrorReplier is implemented by both collection and model requests.
+type pageErrorReplier interface {
+	Error(err error)
+	InvalidQuery(message string)
+}
+
+// replyPageError replies with the RES errors as is, hiding the details of the other ones, such as store errors, which
+// are logged instead.
+func replyPageError(r pageErrorReplier, err error) {
+	var resErr *res.Error
+	switch {
+	case errors.Is(err, errInvalidQuery):
+		r.InvalidQuery(err.Error())
+	case errors.As(err, &resErr):
+		r.Error(resErr)
+	default:
+		r.Error(res.ErrInternalError)
+	}
+}
@@ -0,0 +1,22 @@
+package server
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jirenius/go-res"
+)
+
+// handleRoomAccess controls the access to the resources of a room, whose ID is the capability to read them. The
+// service is zero-knowledge: room IDs are random (version 4) UUIDs only shared between the room members, and the room
+// resources only expose ciphertexts that the room key, which never reaches the service, decrypts. Reading is thus
+// granted to any client presenting a room ID, while no method can be called.
+func (s *Server) handleRoomAccess() res.Option {
+	return res.Access(func(r res.AccessRequest) {
+		roomID, err := uuid.FromString(r.PathParam("roomID"))
+		if err != nil || roomID.Version() != uuid.V4 {
+			r.AccessDenied()
+			return
+		}
+
+		r.Access(true, "")
+	})
+}
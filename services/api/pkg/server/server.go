@@ -2,31 +2,49 @@ package server
 
 import (
 	"context"
+	"errors"
+	"net"
+	"net/http"
 	"sync"
 	"time"
 
 	"github.com/Autherain/go_cyber/internal/health"
 	"github.com/Autherain/go_cyber/internal/logger"
+	"github.com/Autherain/go_cyber/internal/metrics"
+	"github.com/Autherain/go_cyber/internal/pagination"
+	"github.com/Autherain/go_cyber/internal/tracing"
 	"github.com/Autherain/go_cyber/store"
+	"github.com/gofrs/uuid"
 	"github.com/jirenius/go-res"
 	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Server struct {
 	service         *res.Service
 	log             *logger.Logger
 	healthChecker   *health.HealthChecker
+	metrics         *metrics.Metrics
+	healthHTTPAddr  string
+	metricsHTTPAddr string
+	httpServers     []*http.Server
 	wg              sync.WaitGroup
 	shutdownTimeout time.Duration
+	drainDelay      time.Duration
 
 	store *store.Store
+
+	cursorCodec    *pagination.CursorCodec
+	pageSizePolicy pagination.SizePolicy
 }
 
 type Option func(*Server)
 
+const httpReadHeaderTimeout = 5 * time.Second
+
 // New creates a new server instance with the given options
 func New(options ...Option) *Server {
-	s := &Server{}
+	s := &Server{pageSizePolicy: pagination.DefaultSizePolicy}
 
 	for _, option := range options {
 		option(s)
@@ -66,6 +84,27 @@ func WithHealthChecker(healthChecker *health.HealthChecker) Option {
 	}
 }
 
+// WithHealthHTTPAddr serves the health checker probes over HTTP on the given address
+func WithHealthHTTPAddr(addr string) Option {
+	return func(s *Server) {
+		s.healthHTTPAddr = addr
+	}
+}
+
+// WithMetrics instruments the resource handlers, the metrics being served on /metrics of the metrics address
+func WithMetrics(metrics *metrics.Metrics) Option {
+	return func(s *Server) {
+		s.metrics = metrics
+	}
+}
+
+// WithMetricsHTTPAddr serves the metrics over HTTP on the given address, which may be the one of the health probes
+func WithMetricsHTTPAddr(addr string) Option {
+	return func(s *Server) {
+		s.metricsHTTPAddr = addr
+	}
+}
+
 // WithShutdownTimeout sets the shutdown timeout
 func WithShutdownTimeout(timeout time.Duration) Option {
 	return func(s *Server) {
@@ -73,22 +112,50 @@ func WithShutdownTimeout(timeout time.Duration) Option {
 	}
 }
 
+// WithDrainDelay sets how long the service keeps reporting unready before it stops, so that probes notice it
+func WithDrainDelay(delay time.Duration) Option {
+	return func(s *Server) {
+		s.drainDelay = delay
+	}
+}
+
 func WithStore(store *store.Store) Option {
 	return func(s *Server) {
 		s.store = store
 	}
 }
 
+// WithCursorCodec sets the codec of the pagination cursors
+func WithCursorCodec(codec *pagination.CursorCodec) Option {
+	return func(s *Server) {
+		s.cursorCodec = codec
+	}
+}
+
+// WithPageSizePolicy sets the page size bounds of the paginated collections
+func WithPageSizePolicy(policy pagination.SizePolicy) Option {
+	return func(s *Server) {
+		s.pageSizePolicy = policy
+	}
+}
+
 func (s *Server) Start(ctx context.Context, natsConn *nats.Conn) error {
 	s.log.Info("Starting application")
 
 	errChan := make(chan error, 1)
 
-	// Start health checker if enabled
+	// Start health checker if enabled, the service becoming ready once it serves
 	if s.healthChecker != nil {
+		s.service.SetOnServe(func(*res.Service) {
+			s.healthChecker.SetReady(true)
+		})
 		s.startHealthChecker(errChan)
 	}
 
+	if err := s.startHTTPServers(errChan); err != nil {
+		return err
+	}
+
 	// Start service
 	s.wg.Add(1)
 	go func() {
@@ -113,6 +180,51 @@ func (s *Server) startHealthChecker(errChan chan error) {
 	}()
 }
 
+// startHTTPServers serves the health probes and the metrics, sharing a server when they have the same address
+func (s *Server) startHTTPServers(errChan chan error) error {
+	muxes := make(map[string]*http.ServeMux)
+	mux := func(addr string) *http.ServeMux {
+		if muxes[addr] == nil {
+			muxes[addr] = http.NewServeMux()
+		}
+		return muxes[addr]
+	}
+
+	if s.healthChecker != nil && s.healthHTTPAddr != "" {
+		mux(s.healthHTTPAddr).Handle("/", s.healthChecker.Handler())
+	}
+	if s.metrics != nil && s.metricsHTTPAddr != "" {
+		mux(s.metricsHTTPAddr).Handle("GET /metrics", s.metrics.Handler())
+	}
+
+	for addr, mux := range muxes {
+		httpServer := &http.Server{
+			Addr:              addr,
+			Handler:           mux,
+			ReadHeaderTimeout: httpReadHeaderTimeout,
+		}
+
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+		s.log.Info("Serving over HTTP", "addr", listener.Addr().String(),
+			"health", addr == s.healthHTTPAddr, "metrics", addr == s.metricsHTTPAddr)
+		s.httpServers = append(s.httpServers, httpServer)
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				s.log.Error("HTTP server error", "error", err)
+				errChan <- err
+			}
+		}()
+	}
+
+	return nil
+}
+
 func (s *Server) handleShutdown(ctx context.Context, errChan chan error) error {
 	select {
 	case err := <-errChan:
@@ -124,17 +236,30 @@ func (s *Server) handleShutdown(ctx context.Context, errChan chan error) error {
 }
 
 func (s *Server) shutdown() error {
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
-	defer cancel()
-
+	// Stop reporting ready first, and keep answering the probes for the drain delay so that they see the service unready
+	// and traffic drains before the service goes away
 	if s.healthChecker != nil {
+		s.healthChecker.SetReady(false)
+		if s.drainDelay > 0 {
+			s.log.Info("Draining before shutdown", "delay", s.drainDelay)
+			time.Sleep(s.drainDelay)
+		}
 		s.healthChecker.Stop()
 	}
 
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
 	if err := s.service.Shutdown(); err != nil {
 		s.log.Error("Error stopping RES service", "error", err)
 	}
 
+	for _, httpServer := range s.httpServers {
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			s.log.Error("Error stopping HTTP server", "addr", httpServer.Addr, "error", err)
+		}
+	}
+
 	done := make(chan struct{})
 	go func() {
 		s.wg.Wait()
@@ -153,14 +278,67 @@ func (s *Server) shutdown() error {
 
 func (s *Server) addResourceHandlers() {
 	// Add your resource handlers here
-	s.service.Handle(
+	// Patterns are relative to the service name
+	s.handle(
+		"room.$roomID.messages",
+		s.handleRoomAccess(),
+		s.handleRoomMessagesRequest(),
+	)
+	s.handle(
+		"room.$roomID.messages.page",
+		s.handleRoomAccess(),
+		s.handleRoomMessagesPageRequest(),
+	)
+	s.handle(
 		"api.>",
 		s.handleAPIRequest(),
 	)
 }
 
+// handle registers the handler options for the resource pattern, instrumenting them when metrics are set
+func (s *Server) handle(pattern string, options ...res.Option) {
+	if s.metrics != nil {
+		options = append(options, s.metrics.InstrumentRES(pattern))
+	}
+	s.service.Handle(pattern, options...)
+}
+
+// Request types, as named in the request spans
+const (
+	requestTypeGet = "get"
+)
+
+// startRequest starts the span of a RES request and returns the context to pass down to the store, which carries a
+// logger enriched with the request details
+func (s *Server) startRequest(r res.Resource, requestType, method string) (context.Context, trace.Span) {
+	ctx, span := tracing.StartRESSpan(context.Background(), r, requestType, method)
+	return logger.NewContext(ctx, s.requestLogger(r, requestType, method, span)), span
+}
+
+// requestLogger returns a child logger identifying the RES request, its connection and its trace
+func (s *Server) requestLogger(r res.Resource, requestType, method string, span trace.Span) *logger.Logger {
+	args := []any{"rid", r.ResourceName(), "type", requestType}
+	if method != "" {
+		args = append(args, "method", method)
+	}
+	if req, ok := r.(interface{ CID() string }); ok {
+		args = append(args, "cid", req.CID())
+	}
+	if requestID, err := uuid.NewV4(); err == nil {
+		args = append(args, "requestId", requestID.String())
+	}
+	if spanContext := span.SpanContext(); spanContext.IsValid() {
+		args = append(args, "traceId", spanContext.TraceID().String())
+	}
+
+	return s.log.With(args...)
+}
+
 func (s *Server) handleAPIRequest() res.Option {
 	return res.GetModel(func(r res.ModelRequest) {
+		_, span := s.startRequest(r, requestTypeGet, "")
+		defer span.End()
+
 		r.Model(map[string]interface{}{
 			"message": "Hello from API",
 			"time":    time.Now().Format(time.RFC3339),
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"time"
 
 	"github.com/gofrs/uuid"
@@ -18,7 +19,8 @@ type RoomSelector struct {
 }
 
 type RoomManager interface {
-	ReadRoom(selector *RoomSelector) (*Room, error)
-	CreateRoom(selector *RoomSelector) error
-	DeleteRoom(selector *RoomSelector) error
+	ReadRoom(ctx context.Context, selector *RoomSelector) (*Room, error)
+	CreateRoom(ctx context.Context, selector *RoomSelector) error
+	DeleteRoom(ctx context.Context, selector *RoomSelector) error
+	CountActiveRooms(ctx context.Context) (int64, error)
 }
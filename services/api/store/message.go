@@ -1,11 +1,145 @@
 package store
 
-import api "github.com/Autherain/go_cyber"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	api "github.com/Autherain/go_cyber"
+	"github.com/Autherain/go_cyber/internal/pagination"
+	"github.com/Autherain/go_cyber/store/models"
+	"github.com/gofrs/uuid"
+	"github.com/volatiletech/sqlboiler/v4/queries/qm"
+)
 
 type messageStore struct{ baseStore *Store }
 
 var _ api.MessageManager = (*messageStore)(nil)
 
-func (s *messageStore) ReadMessages(selector *api.MessagesSelector) (*[]api.Message, error) {
-	return nil, nil
+// ReadMessages reads a page of the room messages in chronological order, whatever the direction of the selector. One
+// extra row is read to tell whether more messages follow the page. Messages without a timestamp are left out.
+func (s *messageStore) ReadMessages(
+	ctx context.Context,
+	selector *api.MessagesSelector,
+) (_ *[]api.Message, _ *pagination.PageInfo[api.MessageKey], err error) {
+	ctx, end := s.baseStore.startQuery(ctx, "ReadMessages")
+	defer end(&err)
+
+	comparison, order := ">", "ASC"
+	if selector.Direction == pagination.Before {
+		comparison, order = "<", "DESC"
+	}
+
+	mods := []qm.QueryMod{
+		models.MessageWhere.RoomID.EQ(selector.RoomID.String()),
+		// Without a timestamp a message has no keyset position, and ending a page with it would restart the pagination
+		models.MessageWhere.Timestamp.IsNotNull(),
+	}
+	if !selector.Key.IsZero() {
+		mods = append(mods, qm.Where(
+			fmt.Sprintf("(%s, %s) %s (?, ?)", models.MessageColumns.Timestamp, models.MessageColumns.ID, comparison),
+			selector.Key.Timestamp,
+			selector.Key.ID.String(),
+		))
+	}
+	mods = append(mods,
+		qm.OrderBy(fmt.Sprintf("%s %s, %s %s",
+			models.MessageColumns.Timestamp, order, models.MessageColumns.ID, order,
+		)),
+		qm.Limit(selector.Size+1),
+	)
+
+	rows, err := models.Messages(mods...).All(ctx, s.baseStore.db)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read messages: %w", err)
+	}
+
+	info := &pagination.PageInfo[api.MessageKey]{HasMore: len(rows) > selector.Size}
+	if info.HasMore {
+		rows = rows[:selector.Size]
+	}
+
+	messages := make([]api.Message, 0, len(rows))
+	for _, row := range rows {
+		message, err := newMessage(row)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		messages = append(messages, message)
+	}
+
+	if len(messages) > 0 {
+		last := messages[len(messages)-1]
+		info.NextKey = api.MessageKey{Timestamp: last.Timestamp, ID: last.ID}
+	}
+
+	if selector.EstimateTotal {
+		total, err := s.estimateRoomMessages(ctx, selector.RoomID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		info.EstimatedTotal = &total
+	}
+
+	if selector.Direction == pagination.Before {
+		slices.Reverse(messages)
+	}
+
+	return &messages, info, nil
+}
+
+// estimateRoomMessages estimates the number of messages of a room from the planner statistics, which avoids counting
+// the whole room history.
+func (s *messageStore) estimateRoomMessages(ctx context.Context, roomID uuid.UUID) (int64, error) {
+	var rawPlan []byte
+
+	err := s.baseStore.db.QueryRowContext(
+		ctx,
+		fmt.Sprintf(
+			"EXPLAIN (FORMAT JSON) SELECT 1 FROM %s WHERE %s = $1",
+			models.TableNames.Messages, models.MessageColumns.RoomID,
+		),
+		roomID.String(),
+	).Scan(&rawPlan)
+	if err != nil {
+		return 0, fmt.Errorf("could not estimate room messages: %w", err)
+	}
+
+	var plans []struct {
+		Plan struct {
+			Rows int64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(rawPlan, &plans); err != nil {
+		return 0, fmt.Errorf("could not parse room messages estimate: %w", err)
+	}
+	if len(plans) == 0 {
+		return 0, errors.New("could not parse room messages estimate: empty plan")
+	}
+
+	return plans[0].Plan.Rows, nil
+}
+
+func newMessage(row *models.Message) (api.Message, error) {
+	id, err := uuid.FromString(row.ID)
+	if err != nil {
+		return api.Message{}, fmt.Errorf("invalid message ID %q: %w", row.ID, err)
+	}
+
+	roomID, err := uuid.FromString(row.RoomID)
+	if err != nil {
+		return api.Message{}, fmt.Errorf("invalid room ID %q: %w", row.RoomID, err)
+	}
+
+	return api.Message{
+		ID:               id,
+		RoomID:           roomID,
+		EncryptedContent: row.EncryptedContent,
+		Nonce:            row.Nonce,
+		Timestamp:        row.Timestamp.Time,
+	}, nil
 }
@@ -1,19 +1,38 @@
 package store
 
-import api "github.com/Autherain/go_cyber"
+import (
+	"context"
+	"fmt"
+
+	api "github.com/Autherain/go_cyber"
+	"github.com/Autherain/go_cyber/store/models"
+	"github.com/volatiletech/null/v8"
+)
 
 type roomStore struct{ baseStore *Store }
 
 var _ api.RoomManager = (*roomStore)(nil)
 
-func (s *roomStore) ReadRoom(selector *api.RoomSelector) (*api.Room, error) {
+func (s *roomStore) ReadRoom(ctx context.Context, selector *api.RoomSelector) (*api.Room, error) {
 	return nil, nil
 }
 
-func (s *roomStore) CreateRoom(selector *api.RoomSelector) error {
+func (s *roomStore) CreateRoom(ctx context.Context, selector *api.RoomSelector) error {
 	return nil
 }
 
-func (s *roomStore) DeleteRoom(selector *api.RoomSelector) error {
+func (s *roomStore) DeleteRoom(ctx context.Context, selector *api.RoomSelector) error {
 	return nil
 }
+
+func (s *roomStore) CountActiveRooms(ctx context.Context) (count int64, err error) {
+	ctx, end := s.baseStore.startQuery(ctx, "CountActiveRooms")
+	defer end(&err)
+
+	count, err = models.Rooms(models.RoomWhere.IsActive.EQ(null.BoolFrom(true))).Count(ctx, s.baseStore.db)
+	if err != nil {
+		return 0, fmt.Errorf("could not count active rooms: %w", err)
+	}
+
+	return count, nil
+}
@@ -1,13 +1,25 @@
 package store
 
 import (
+	"context"
 	"database/sql"
+	"time"
 
 	api "github.com/Autherain/go_cyber"
+	"github.com/Autherain/go_cyber/internal/logger"
+	"github.com/Autherain/go_cyber/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// QueryObserver is notified of the duration and outcome of each store method call
+type QueryObserver interface {
+	ObserveQuery(method string, duration time.Duration, err error)
+}
+
 type Store struct {
-	db *sql.DB
+	db       *sql.DB
+	observer QueryObserver
 
 	Rooms    api.RoomManager
 	Messages api.MessageManager
@@ -37,3 +49,38 @@ func WithDB(db *sql.DB) Option {
 		s.db = db
 	}
 }
+
+func WithQueryObserver(observer QueryObserver) Option {
+	return func(s *Store) {
+		s.observer = observer
+	}
+}
+
+// startQuery starts the span of a store method call, returning the function that ends it. That function logs the call
+// with the request logger of the context, reports it to the query observer, if any, and is meant to be deferred with a
+// pointer to the named error result of the method.
+func (s *Store) startQuery(ctx context.Context, method string) (context.Context, func(err *error)) {
+	start := time.Now()
+	ctx, span := tracing.Tracer().Start(ctx, "store."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system", "postgresql")),
+	)
+
+	return ctx, func(err *error) {
+		tracing.End(span, *err)
+		duration := time.Since(start)
+		if *err != nil {
+			queryLogger(ctx).Debug("Store query failed", "method", method, "duration", duration, "error", *err)
+		} else {
+			queryLogger(ctx).Debug("Store query", "method", method, "duration", duration)
+		}
+		if s.observer != nil {
+			s.observer.ObserveQuery(method, duration, *err)
+		}
+	}
+}
+
+// queryLogger returns the store logger of the request carried by the context.
+func queryLogger(ctx context.Context) *logger.Logger {
+	return logger.FromContext(ctx).Component("store")
+}